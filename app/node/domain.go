@@ -0,0 +1,290 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+var (
+	// propagationInterval is the time between two propagation checks
+	propagationInterval = time.Second * 5
+	// retryInterval is the time to wait before retrying a transient ddns failure, rate limits wait twice as long
+	retryInterval = time.Second * 5
+)
+
+// Update domain records, and wait for the changed records to propagate. The failures are notified, the success is
+// reported by the rotation event.
+func (n *Node) updateDomain() []string {
+	var (
+		errs    []error
+		changed []*Domain
+	)
+	ip, _ := n.state()
+	for _, d := range n.Domains {
+		res, err := n.updateDomainRecord(d, ip)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Name, err))
+			continue
+		}
+
+		n.Logger.Infof("%s record %s: %s", d.Name, res, ip)
+		if res.Changed() {
+			changed = append(changed, d)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		n.Logger.Error(err)
+		n.notify(n.event(notify.EventDDNSFailed).WithError(err))
+	}
+
+	return n.waitPropagation(changed, ip)
+}
+
+// updateDomainRecord updates the domain record to ip, only temporary failures are retried
+func (n *Node) updateDomainRecord(d *Domain, ip string) (ddns.Result, error) {
+	if d.DdnsClient == nil {
+		return ddns.Unchanged, errors.New("ddns client is null")
+	}
+
+	var (
+		res ddns.Result
+		err error
+	)
+	for i := 0; i < 3; i++ {
+		if res, err = d.DdnsClient.AddUpdateDomainRecords(n.Network, d.Name, ip); err == nil || !ddns.IsTemporary(err) {
+			return res, err
+		}
+
+		n.Logger.Warnf("%s: %v, attempt retry.. (%d/3)", d.Name, err, i+1)
+		if errors.Is(err, ddns.ErrRateLimited) {
+			time.Sleep(retryInterval * 2)
+		} else {
+			time.Sleep(retryInterval)
+		}
+	}
+
+	return res, err
+}
+
+// Failover points the domains to the standby while the node is blocked, so clients are served during the IP rotation.
+// Only the changed records are notified, as it is called on every check while the node stays blocked.
+func (n *Node) Failover() error {
+	ip := n.standbyIP()
+	if ip == "" {
+		return nil
+	}
+
+	var (
+		errs    []error
+		details []string
+	)
+	for _, d := range n.Domains {
+		res, err := n.updateDomainRecord(d, ip)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Name, err))
+			continue
+		}
+		if res.Changed() {
+			n.Logger.Warnf("%s record %s: failover to standby %s", d.Name, res, ip)
+			details = append(details, fmt.Sprintf("%s: failover to standby %s", d.Name, ip))
+		}
+	}
+	n.notifyDomains(details, errs)
+
+	return errors.Join(errs...)
+}
+
+// standbyIP returns the IP of the standby node if it is healthy, or the fixed standby IP
+func (n *Node) standbyIP() string {
+	if s := n.StandbyNode; s != nil {
+		if ip, blocked := s.state(); ip != "" && !blocked {
+			return ip
+		}
+	}
+	return n.StandbyIP
+}
+
+// servingIP returns the IP that the domains should point to, which is the standby one while the node is blocked
+func (n *Node) servingIP() string {
+	ip, blocked := n.state()
+	if blocked {
+		if standby := n.standbyIP(); standby != "" {
+			return standby
+		}
+	}
+	return ip
+}
+
+// waitPropagation waits for ip to be visible on the nameservers of every domain, and reports how long it took. The
+// proxied domains never resolve to ip, they are skipped.
+func (n *Node) waitPropagation(domains []*Domain, ip string) []string {
+	domains = slices.DeleteFunc(slices.Clone(domains), func(d *Domain) bool { return ddns.IsProxied(d.DdnsClient) })
+	if n.PropagationTimeout <= 0 || len(domains) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.PropagationTimeout)
+	defer cancel()
+
+	report := make([]string, len(domains))
+	var wg sync.WaitGroup
+	for i := range domains {
+		wg.Add(1)
+		go func(i int, d *Domain) {
+			defer wg.Done()
+
+			ns, err := ddns.Nameservers(ctx, n.Nameserver, d.Name)
+			if err != nil {
+				n.Logger.Error(err)
+				report[i] = fmt.Sprintf("%s: propagation unknown", d.Name)
+				return
+			}
+
+			elapsed, err := ddns.WaitPropagation(ctx, ns, recordType(n.Network), d.Name, ip, propagationInterval)
+			if err != nil {
+				n.Logger.Warn(err)
+				report[i] = fmt.Sprintf("%s: not propagated after %s", d.Name, elapsed.Round(time.Second))
+				return
+			}
+
+			n.Logger.Infof("%s propagated in %s", d.Name, elapsed.Round(time.Second))
+			report[i] = fmt.Sprintf("%s: propagated in %s", d.Name, elapsed.Round(time.Second))
+		}(i, domains[i])
+	}
+	wg.Wait()
+
+	return report
+}
+
+// UpdateDomainIp reconciles every domain with the serving IP, a domain which resolves to exactly that IP is left untouched.
+// The records of a network that the node does not serve are removed.
+func (n *Node) UpdateDomainIp() error {
+	var (
+		errs    []error
+		details []string
+	)
+	for _, d := range n.Domains {
+		changed, err := n.updateDomainIp(d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Name, err))
+		}
+		if changed {
+			details = append(details, fmt.Sprintf("%s: %s", d.Name, n.servingIP()))
+		}
+		if err := n.removeUnservedRecords(d); err != nil && !errors.Is(err, ddns.ErrNotSupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Name, err))
+		}
+	}
+	n.notifyDomains(details, errs)
+
+	return errors.Join(errs...)
+}
+
+// notifyDomains notifies the updated domains and the failures
+func (n *Node) notifyDomains(details []string, errs []error) {
+	if len(details) > 0 {
+		e := n.event(notify.EventDDNSUpdated)
+		e.Details = details
+		n.notify(e)
+	}
+	if len(errs) > 0 {
+		n.notify(n.event(notify.EventDDNSFailed).WithError(errors.Join(errs...)))
+	}
+}
+
+// updateDomainIp converges the domain records to the serving IP, and reports whether the records are changed, even
+// partially on error
+func (n *Node) updateDomainIp(d *Domain) (bool, error) {
+	if d.DdnsClient == nil {
+		return false, errors.New("ddns client is null")
+	}
+
+	ip := n.servingIP()
+
+	// check domain resolution sync with ip, a proxied domain resolves to the proxy and is reconciled with the provider
+	var ips map[string]bool
+	if !ddns.IsProxied(d.DdnsClient) {
+		var err error
+		if ips, err = n.resolve(d, recordType(n.Network)); err != nil {
+			n.Logger.Debugf("Resolve %s: %v", d.Name, err)
+		} else if len(ips) == 1 && ips[ip] {
+			return false, nil
+		}
+	}
+
+	// converge domain records to ip
+	res, err := d.DdnsClient.Reconcile(recordType(n.Network), d.Name, []string{ip})
+	if err != nil {
+		return res.Changed(), err
+	}
+	if res.Changed() {
+		n.Logger.Infof("%s record %s: %s", d.Name, res, ip)
+	} else if ips != nil {
+		n.Logger.Infof("%s record is %s but not propagated yet", d.Name, ip)
+	}
+
+	return res.Changed(), nil
+}
+
+// removeUnservedRecords removes the records of the other address family when nothing serves it, the records served by
+// another node or pool are kept
+func (n *Node) removeUnservedRecords(d *Domain) error {
+	other := "tcp6"
+	if n.Network == "tcp6" {
+		other = "tcp4"
+	}
+	if d.DdnsClient == nil || !d.Exclusive || slices.Contains(n.networks, other) {
+		return nil
+	}
+
+	// only call the provider when there are records to remove, an unresolvable domain is left to the next check. A
+	// proxied domain resolves on both networks, it is left to the provider.
+	if !ddns.IsProxied(d.DdnsClient) {
+		ips, err := n.resolve(d, recordType(other))
+		if err != nil {
+			n.Logger.Debugf("Resolve %s %s: %v", d.Name, recordType(other), err)
+			return nil
+		}
+		if len(ips) == 0 {
+			return nil
+		}
+	}
+
+	res, err := d.DdnsClient.Reconcile(recordType(other), d.Name, nil)
+	if err != nil {
+		return err
+	}
+	if res.Changed() {
+		n.Logger.Infof("%s %s records are removed", d.Name, recordType(other))
+	}
+
+	return nil
+}
+
+// resolve looks up the domain against the first of its nameservers
+func (n *Node) resolve(d *Domain, rrType string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.Timeout)
+	defer cancel()
+
+	ns, err := ddns.Nameservers(ctx, n.Nameserver, d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddns.Resolve(ctx, ns[0], rrType, d.Name)
+}
+
+func recordType(network string) string {
+	if network == "tcp6" {
+		return "AAAA"
+	}
+	return "A"
+}
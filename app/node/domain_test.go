@@ -0,0 +1,104 @@
+package node
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// recordNotify records the sent events
+type recordNotify struct {
+	mu     sync.Mutex
+	events []*notify.Event
+}
+
+func (r *recordNotify) Send(e *notify.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestNode_Failover(t *testing.T) {
+	f := &fakeDDNS{records: map[string][]string{}}
+	standby := &Node{Network: "tcp4", ip: "2.2.2.2"}
+	r := &recordNotify{}
+	n := &Node{
+		Notifier:    &notify.Multi{Channels: []*notify.Channel{{Name: "record", Notifier: r}}},
+		Network:     "tcp4",
+		Domains:     []*Domain{{Name: "hk1.example.com", DdnsClient: f}},
+		StandbyIP:   "3.3.3.3",
+		StandbyNode: standby,
+		Logger:      log.WithFields(log.Fields{}),
+		ip:          "1.1.1.1",
+		blocked:     true,
+	}
+
+	// the unchanged records on the next check are not notified again
+	for range 2 {
+		if err := n.Failover(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.records["Ahk1.example.com"]; !slices.Equal(got, []string{"2.2.2.2"}) {
+		t.Errorf("unexpected records: %v", got)
+	}
+	_ = n.Notifier.Close()
+	if len(r.events) != 1 || r.events[0].Type != notify.EventDDNSUpdated {
+		t.Errorf("expected one notification, got %d", len(r.events))
+	}
+	if ip := n.servingIP(); ip != "2.2.2.2" {
+		t.Errorf("unexpected serving ip: %s", ip)
+	}
+
+	// the fixed IP is used when the standby node is blocked too
+	standby.blocked = true
+	if ip := n.servingIP(); ip != "3.3.3.3" {
+		t.Errorf("unexpected serving ip: %s", ip)
+	}
+
+	n.blocked = false
+	if ip := n.servingIP(); ip != "1.1.1.1" {
+		t.Errorf("unexpected serving ip: %s", ip)
+	}
+}
+
+// proxiedDDNS proxies the records, they resolve to the proxy addresses
+type proxiedDDNS struct {
+	*fakeDDNS
+}
+
+func (p *proxiedDDNS) Proxied() bool {
+	return true
+}
+
+func TestNode_Proxied(t *testing.T) {
+	f := &proxiedDDNS{&fakeDDNS{records: map[string][]string{}}}
+	n := &Node{
+		Network:            "tcp4",
+		Domains:            []*Domain{{Name: "hk1.example.com", DdnsClient: f}},
+		Timeout:            time.Second,
+		Nameserver:         "127.0.0.1:1",
+		PropagationTimeout: time.Minute,
+		Logger:             log.WithFields(log.Fields{}),
+		ip:                 "1.1.1.1",
+	}
+
+	// the records are reconciled with the provider without resolving them
+	if err := n.UpdateDomainIp(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.records["Ahk1.example.com"]; !slices.Equal(got, []string{"1.1.1.1"}) {
+		t.Errorf("unexpected records: %v", got)
+	}
+
+	start := time.Now()
+	if report := n.waitPropagation(n.Domains, "1.1.1.1"); report != nil || time.Since(start) > time.Second {
+		t.Errorf("expected the propagation to be skipped, got %v", report)
+	}
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/lightsail"
@@ -10,16 +12,52 @@ import (
 	"github.com/Septrum101/lightsailMon/common/notify"
 )
 
+// Node is a lightsail instance on one network, its domains point to the standby node or IP while it is blocked
 type Node struct {
-	Network    string
-	Svc        *lightsail.Client
-	Timeout    time.Duration
-	DdnsClient ddns.Client
-	Notifier   notify.Notify
-	Logger     *logrus.Entry
+	Network            string
+	Svc                *lightsail.Client
+	Timeout            time.Duration
+	Domains            []*Domain
+	Pool               string
+	StandbyIP          string
+	StandbyNode        *Node
+	Nameserver         string
+	PropagationTimeout time.Duration
+	Notifier           *notify.Multi
+	Logger             *logrus.Entry
+
+	name     string
+	region   string
+	port     int
+	domain   string
+	networks []string
+	paused   atomic.Bool
+	stats    stats
+
+	// mu guards the state below, which is written by the checks and read by the DDNS sync, pools and standbys
+	mu      sync.RWMutex
+	ip      string
+	blocked bool
+	latency int64
+	rotated time.Time
+}
 
-	name   string
-	ip     string
-	port   int
-	domain string
+// Status is a snapshot of the node state
+type Status struct {
+	Name         string
+	Instance     string
+	IP           string
+	Latency      int64
+	Blocked      bool
+	Paused       bool
+	LastRotation time.Time
+}
+
+// Domain is a node domain with the DDNS client that updates it
+type Domain struct {
+	Name       string
+	DDNS       string
+	DdnsClient ddns.Client
+	// Exclusive is set when no other node or pool serves the domain on the other network, its records are removed then
+	Exclusive bool
 }
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,22 +17,46 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/lightsail/types"
 	"github.com/sirupsen/logrus"
 
+	"github.com/Septrum101/lightsailMon/common/notify"
 	cfg "github.com/Septrum101/lightsailMon/config"
 )
 
 func New(configNode *cfg.Node) []*Node {
+	// the legacy single domain is updated by the default DDNS provider
+	configDomains := configNode.Domains
+	if len(configDomains) == 0 && configNode.Domain != "" {
+		configDomains = []*cfg.Domain{{Name: configNode.Domain}}
+	}
+
+	// the first domain names the node in logs and notifications
+	domain := configNode.InstanceName
+	if len(configDomains) > 0 {
+		domain = configDomains[0].Name
+	}
+
 	var nodes []*Node
 	for i := range configNode.Network {
 		network := configNode.Network[i]
 		n := &Node{
-			Timeout: time.Second * 5,
+			Timeout:            time.Second * 5,
+			PropagationTimeout: time.Minute * 2,
 			Logger: logrus.WithFields(map[string]interface{}{
-				"domain": fmt.Sprintf("%s(%s)", configNode.Domain, network),
+				"domain": fmt.Sprintf("%s(%s)", domain, network),
 			}),
-			name:    configNode.InstanceName,
-			Network: network,
-			port:    configNode.Port,
-			domain:  configNode.Domain,
+			name:     configNode.InstanceName,
+			Network:  network,
+			Pool:     configNode.Pool,
+			region:   configNode.Region,
+			port:     configNode.Port,
+			domain:   domain,
+			networks: configNode.Network,
+		}
+		n.stats.since = time.Now()
+		for ii := range configDomains {
+			n.Domains = append(n.Domains, &Domain{
+				Name: configDomains[ii].Name,
+				DDNS: configDomains[ii].DDNS,
+			})
 		}
 
 		// create account session
@@ -62,12 +88,57 @@ func New(configNode *cfg.Node) []*Node {
 			}
 		}
 
+		// the lifetime of the IP found at startup is counted from now
+		n.stats.ipSince = n.stats.since
 		nodes = append(nodes, n)
 	}
 
 	return nodes
 }
 
+// Name returns the lightsail instance name of the node
+func (n *Node) Name() string {
+	return n.name
+}
+
+// Match reports whether name is the instance name or one of the node domains
+func (n *Node) Match(name string) bool {
+	if strings.EqualFold(name, n.name) {
+		return true
+	}
+	return slices.ContainsFunc(n.Domains, func(d *Domain) bool {
+		return strings.EqualFold(name, d.Name)
+	})
+}
+
+// Pause stops checking and rotating the node until it is resumed
+func (n *Node) Pause() {
+	n.paused.Store(true)
+}
+
+func (n *Node) Resume() {
+	n.paused.Store(false)
+}
+
+func (n *Node) Paused() bool {
+	return n.paused.Load()
+}
+
+// Status returns the node state
+func (n *Node) Status() *Status {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return &Status{
+		Name:         n.domain + "(" + n.Network + ")",
+		Instance:     n.name,
+		IP:           n.ip,
+		Latency:      n.latency,
+		Blocked:      n.blocked,
+		Paused:       n.Paused(),
+		LastRotation: n.rotated,
+	}
+}
+
 // attachIP is a helper function to attach static IP to instance
 func (n *Node) attachIP() {
 	n.Logger.Debug("Attach static IP")
@@ -121,6 +192,8 @@ func (n *Node) setIp(ipType string) {
 		return
 	}
 
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	switch ipType {
 	case "ipv4":
 		n.ip = aws.ToString(inst.Instance.PublicIpAddress)
@@ -129,10 +202,25 @@ func (n *Node) setIp(ipType string) {
 	}
 }
 
+// state returns the node IP and whether it is blocked
+func (n *Node) state() (string, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.ip, n.blocked
+}
+
 func (n *Node) RenewIP() {
 	n.Logger.Warn("Change node IP")
-	isSuccess := false
-	for i := 0; i < 3; i++ {
+	oldIP, _ := n.state()
+	n.notify(n.event(notify.EventRotationStarted))
+
+	var (
+		isSuccess bool
+		attempts  int
+		latency   int64
+		err       error
+	)
+	for attempts = 1; attempts <= 3; attempts++ {
 		switch n.Network {
 		case "tcp4":
 			n.attachIP()
@@ -147,68 +235,70 @@ func (n *Node) RenewIP() {
 		}
 
 		// check again connection
-		if _, err := n.checkConnection(); err != nil {
-			n.Logger.Errorf("Renew IP post check: %v attempt retry.. (%d/3)", err, i+1)
+		if latency, err = n.checkConnection(); err != nil {
+			n.Logger.Errorf("Renew IP post check: %v attempt retry.. (%d/3)", err, attempts)
 		} else {
 			n.Logger.Info("Renew IP post check: success")
 			isSuccess = true
 			break
 		}
 	}
-
-	if err := n.updateDomain(); err != nil {
-		n.Logger.Info(err)
+	attempts = min(attempts, 3)
+	n.mu.Lock()
+	n.blocked = !isSuccess
+	n.latency = latency
+	n.rotated = time.Now()
+	newIP := n.ip
+	n.mu.Unlock()
+	n.stats.rotate(newIP != oldIP)
+
+	// the domains stay on the standby until the node is reachable again
+	var propagation []string
+	if isSuccess || n.standbyIP() == "" {
+		propagation = n.updateDomain()
 	}
 
-	if err := n.pushMessage(isSuccess); err != nil {
-		n.Logger.Error(err)
-	} else {
-		n.Logger.Info("Push message success")
+	e := n.event(notify.EventRotationSucceeded)
+	if !isSuccess {
+		e = n.event(notify.EventRotationFailed).WithError(err)
 	}
+	e.OldIP, e.NewIP = oldIP, newIP
+	e.Latency = time.Duration(latency) * time.Millisecond
+	e.Attempts = attempts
+	e.Details = propagation
+	n.notify(e)
 }
 
-// Update domain record
-func (n *Node) updateDomain() error {
-	if n.DdnsClient == nil {
-		return errors.New("ddns client is null")
-	}
-
-	var err error
-	for i := 0; i < 3; i++ {
-		if err = n.DdnsClient.AddUpdateDomainRecords(n.Network, n.domain, n.ip); err != nil {
-			time.Sleep(time.Second * 5)
-			continue
-		}
-
-		return nil
-	}
-
-	return err
+// event creates an event of the node
+func (n *Node) event(t notify.EventType) *notify.Event {
+	e := notify.NewEvent(t)
+	e.Node = n.domain + "(" + n.Network + ")"
+	e.Instance = n.name
+	e.Region = n.region
+	e.Network = n.Network
+	e.IP, _ = n.state()
+	return e
 }
 
-// push message
-func (n *Node) pushMessage(isSuccess bool) error {
+// notify sends the event, a node without notifier only logs it
+func (n *Node) notify(e *notify.Event) {
 	if n.Notifier == nil {
-		return errors.New("notifier is null")
+		n.Logger.Debugf("Notifier is null, skip %s event", e.Type)
+		return
 	}
 
-	if isSuccess {
-		if err := n.Notifier.Webhook(n.domain+"("+n.Network+")", fmt.Sprintf("IP changed: %s", n.ip)); err != nil {
-			return err
-		}
+	// the event is sent in the background, the channel failures are logged by the notifier
+	if err := n.Notifier.Send(e); err != nil {
+		n.Logger.Errorf("Push %s event: %v", e.Type, err)
 	} else {
-		if err := n.Notifier.Webhook(n.domain+"("+n.Network+")", fmt.Sprintf("[%s] Connection block after IP refresh 3 times", n.domain)); err != nil {
-			return err
-		}
+		n.Logger.Debugf("Queue %s event", e.Type)
 	}
-
-	return nil
 }
 
 func (n *Node) checkConnection() (int64, error) {
-	addr := n.ip
+	addr, _ := n.state()
 	if n.Network == "tcp6" {
-		addr = "[" + n.ip + "]"
+		addr = "[" + addr + "]"
 	}
 
 	d, conn, err := dialWithRetry(n.Network, addr+":"+strconv.Itoa(n.port), n.Timeout, 3, 5*time.Second)
@@ -220,48 +310,29 @@ func (n *Node) checkConnection() (int64, error) {
 	return d, err
 }
 
-func (n *Node) UpdateDomainIp() error {
-	if n.DdnsClient == nil {
-		return errors.New("ddns client is null")
-	}
-
-	// check domain sync with ip
-	var (
-		domainIps map[string]bool
-		err       error
-	)
-
-	switch n.Network {
-	case "tcp4":
-		domainIps, err = n.DdnsClient.GetDomainRecords("A", n.domain)
-	case "tcp6":
-		domainIps, err = n.DdnsClient.GetDomainRecords("AAAA", n.domain)
-	}
-	if err != nil {
-		return err
-	}
-
-	if _, ok := domainIps[n.ip]; !ok {
-		if err := n.DdnsClient.AddUpdateDomainRecords(n.Network, n.domain, n.ip); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func (n *Node) IsBlock() bool {
 	delay, err := n.checkConnection()
 	if err != nil {
 		if pathErr, ok := errors.AsType[*net.OpError](err); ok && pathErr.Addr != nil {
 			n.Logger.Errorf("after 3 attempts, last error: %s", err)
+			n.mu.Lock()
+			n.blocked = true
+			n.mu.Unlock()
+			n.stats.probe(0, false, true)
+			n.notify(n.event(notify.EventNodeBlocked).WithError(err))
 			return true
 		}
 		n.Logger.Errorf("after 3 attempts, last error: %s", err)
+		n.stats.probe(0, false, false)
 		return false
 	}
 
 	n.Logger.Infof("Tcping: %d ms", delay)
+	n.stats.probe(delay, true, false)
+	n.mu.Lock()
+	n.blocked = false
+	n.latency = delay
+	n.mu.Unlock()
 	return false
 }
 
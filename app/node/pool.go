@@ -0,0 +1,71 @@
+package node
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+// Pool is a domain served by several nodes of one network, the domain records are kept equal to the healthy member IPs
+type Pool struct {
+	Name       string
+	Domain     string
+	Network    string
+	Members    []*Node
+	DdnsClient ddns.Client
+	Logger     *logrus.Entry
+}
+
+func NewPool(name string, domain string, network string, members []*Node) *Pool {
+	return &Pool{
+		Name:    name,
+		Domain:  domain,
+		Network: network,
+		Members: members,
+		Logger: logrus.WithFields(map[string]interface{}{
+			"domain": domain + "(" + network + ")",
+		}),
+	}
+}
+
+// Sync reconciles the pool domain records with the IPs of the members that are not blocked.
+// The records are kept as they are if no member is healthy, an empty domain helps no one.
+func (p *Pool) Sync() error {
+	if p.DdnsClient == nil {
+		return errors.New("ddns client is null")
+	}
+
+	ips := p.healthyIPs()
+	if len(ips) == 0 {
+		p.Logger.Warn("No healthy member in pool, keep the records")
+		return nil
+	}
+
+	res, err := p.DdnsClient.Reconcile(recordType(p.Network), p.Domain, ips)
+	if err != nil {
+		return err
+	}
+	if res.Changed() {
+		p.Logger.Infof("Pool record %s: %v", res, ips)
+	}
+
+	return nil
+}
+
+// healthyIPs returns the distinct IPs of the members that are not blocked
+func (p *Pool) healthyIPs() []string {
+	var ips []string
+	seen := make(map[string]bool)
+	for _, n := range p.Members {
+		ip, blocked := n.state()
+		if ip == "" || blocked || seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		ips = append(ips, ip)
+	}
+
+	return ips
+}
@@ -0,0 +1,82 @@
+package node
+
+import (
+	"slices"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+type fakeDDNS struct {
+	records map[string][]string
+}
+
+func (f *fakeDDNS) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	return f.Reconcile("A", domain, []string{ipAddr})
+}
+
+func (f *fakeDDNS) GetDomainRecords(recordType string, domain string) (map[string]bool, error) {
+	ips := make(map[string]bool)
+	for _, ip := range f.records[recordType+domain] {
+		ips[ip] = true
+	}
+	return ips, nil
+}
+
+func (f *fakeDDNS) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
+	if slices.Equal(f.records[recordType+domain], ips) {
+		return ddns.Unchanged, nil
+	}
+	f.records[recordType+domain] = ips
+	return ddns.Updated, nil
+}
+
+func TestPool_Sync(t *testing.T) {
+	members := []*Node{
+		{Network: "tcp4", ip: "1.1.1.1"},
+		{Network: "tcp4", ip: "2.2.2.2"},
+		{Network: "tcp4", ip: "3.3.3.3"},
+	}
+	f := &fakeDDNS{records: map[string][]string{}}
+	p := NewPool("hk", "hk.example.com", "tcp4", members)
+	p.DdnsClient = f
+	p.Logger = log.WithFields(log.Fields{})
+
+	if err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.records["Ahk.example.com"]; !slices.Equal(got, []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}) {
+		t.Errorf("unexpected records: %v", got)
+	}
+
+	// a blocked member is pulled from the pool
+	members[1].blocked = true
+	if err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.records["Ahk.example.com"]; !slices.Equal(got, []string{"1.1.1.1", "3.3.3.3"}) {
+		t.Errorf("unexpected records: %v", got)
+	}
+
+	// and re-added with its new IP once it is healthy
+	members[1].ip, members[1].blocked = "4.4.4.4", false
+	if err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.records["Ahk.example.com"]; !slices.Equal(got, []string{"1.1.1.1", "4.4.4.4", "3.3.3.3"}) {
+		t.Errorf("unexpected records: %v", got)
+	}
+
+	// the records are kept when every member is blocked
+	for _, n := range members {
+		n.blocked = true
+	}
+	if err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.records["Ahk.example.com"]; len(got) != 3 {
+		t.Errorf("unexpected records: %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package node
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxSamples caps the latency samples kept between two reports
+const maxSamples = 10000
+
+// Stats summarizes the node checks since the last report
+type Stats struct {
+	Name       string
+	IP         string
+	Checks     int
+	Uptime     float64 // the percent of the checks the node was reachable
+	Blocks     int
+	Rotations  int
+	AvgLatency int64
+	P95Latency int64
+	IPLifetime time.Duration
+	Since      time.Time
+}
+
+// stats are the counters of the node since the last report
+type stats struct {
+	mu        sync.Mutex
+	since     time.Time
+	checks    int
+	up        int
+	blocks    int
+	rotations int
+	latencies []int64
+	ipSince   time.Time
+}
+
+func (s *stats) probe(latency int64, up bool, blocked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks++
+	if up {
+		s.up++
+		if len(s.latencies) < maxSamples {
+			s.latencies = append(s.latencies, latency)
+		}
+	}
+	if blocked {
+		s.blocks++
+	}
+}
+
+func (s *stats) rotate(changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotations++
+	if changed {
+		s.ipSince = time.Now()
+	}
+}
+
+// Stats returns the node stats since the last call, and resets the counters
+func (n *Node) Stats() *Stats {
+	s := &n.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ip, _ := n.state()
+	now := time.Now()
+	rtn := &Stats{
+		Name:      n.domain + "(" + n.Network + ")",
+		IP:        ip,
+		Checks:    s.checks,
+		Blocks:    s.blocks,
+		Rotations: s.rotations,
+		Since:     s.since,
+	}
+	if s.checks > 0 {
+		rtn.Uptime = float64(s.up) * 100 / float64(s.checks)
+	}
+	if !s.ipSince.IsZero() {
+		rtn.IPLifetime = now.Sub(s.ipSince)
+	}
+	if len(s.latencies) > 0 {
+		var sum int64
+		for _, l := range s.latencies {
+			sum += l
+		}
+		rtn.AvgLatency = sum / int64(len(s.latencies))
+
+		sorted := slices.Sorted(slices.Values(s.latencies))
+		rtn.P95Latency = sorted[(len(sorted)*95+99)/100-1]
+	}
+
+	s.since = now
+	s.checks, s.up, s.blocks, s.rotations = 0, 0, 0, 0
+	s.latencies = nil
+	return rtn
+}
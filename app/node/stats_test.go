@@ -0,0 +1,36 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNode_Stats(t *testing.T) {
+	n := &Node{Network: "tcp4", domain: "node1.test.com", ip: "1.1.1.1"}
+	n.stats.since = time.Now().Add(-time.Hour)
+	n.stats.ipSince = n.stats.since
+
+	for i := int64(1); i <= 18; i++ {
+		n.stats.probe(i*10, true, false)
+	}
+	n.stats.probe(0, false, true)
+	n.stats.probe(0, false, false)
+	n.stats.rotate(true)
+
+	st := n.Stats()
+	if st.Name != "node1.test.com(tcp4)" || st.Checks != 20 || st.Uptime != 90 || st.Blocks != 1 || st.Rotations != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if st.AvgLatency != 95 || st.P95Latency != 180 {
+		t.Errorf("unexpected latency: avg %d, p95 %d", st.AvgLatency, st.P95Latency)
+	}
+	if st.IPLifetime > time.Minute {
+		t.Errorf("expected the lifetime of the rotated IP, got %s", st.IPLifetime)
+	}
+
+	// the counters are reset after the report
+	st = n.Stats()
+	if st.Checks != 0 || st.Rotations != 0 || st.IPLifetime == 0 || time.Since(st.Since) > time.Minute {
+		t.Errorf("unexpected stats after reset: %+v", st)
+	}
+}
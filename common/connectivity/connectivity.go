@@ -0,0 +1,215 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// defaultTimeout is the check timeout when it is not set, the same as the node check timeout
+const defaultTimeout = time.Second * 5
+
+// Target is a connectivity check target
+type Target interface {
+	Check(ctx context.Context, network string) error
+	String() string
+}
+
+// Checker checks the targets on one network, the network is up when the number of reachable targets meets the policy
+type Checker struct {
+	Network string // tcp4 or tcp6
+	Targets []Target
+	Policy  string        // any, all, majority or the minimum number of reachable targets, default: any
+	Timeout time.Duration // default: 5s
+}
+
+func New(network string, targets []string, policy string, timeout time.Duration) (*Checker, error) {
+	c := &Checker{Network: network, Policy: policy, Timeout: timeout}
+	if _, err := c.required(len(targets)); err != nil {
+		return nil, err
+	}
+
+	for _, t := range targets {
+		target, err := ParseTarget(t)
+		if err != nil {
+			return nil, err
+		}
+		c.Targets = append(c.Targets, target)
+	}
+	if len(c.Targets) == 0 {
+		return nil, fmt.Errorf("no %s connectivity target", network)
+	}
+
+	return c, nil
+}
+
+// ParseTarget parses an HTTP url, a "tcp://host:port" or "host:port" address, or a "dns://server:port/domain" query
+func ParseTarget(s string) (Target, error) {
+	if !strings.Contains(s, "://") {
+		s = "tcp://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpTarget{url: s}, nil
+	case "tcp":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("missing port in target: %s", s)
+		}
+		return &tcpTarget{addr: u.Host}, nil
+	case "dns":
+		domain := strings.TrimPrefix(u.Path, "/")
+		if domain == "" {
+			return nil, fmt.Errorf("missing domain in target: %s", s)
+		}
+		server := u.Host
+		if u.Port() == "" {
+			server = net.JoinHostPort(strings.Trim(u.Host, "[]"), "53")
+		}
+		return &dnsTarget{server: server, domain: domain}, nil
+	default:
+		return nil, fmt.Errorf("not support connectivity target: %s", s)
+	}
+}
+
+// Check checks the targets in parallel, and returns the errors of the unreachable ones if the policy is not met
+func (c *Checker) Check(ctx context.Context) error {
+	required, err := c.required(len(c.Targets))
+	if err != nil {
+		return err
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		ok   int
+		errs []error
+	)
+	for _, t := range c.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := t.Check(ctx, c.Network)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", t, err))
+			} else {
+				ok++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ok >= required {
+		return nil
+	}
+	return fmt.Errorf("%d/%d targets reachable: %w", ok, len(c.Targets), errors.Join(errs...))
+}
+
+// required returns the number of reachable targets the policy needs
+func (c *Checker) required(total int) (int, error) {
+	switch c.Policy {
+	case "", "any":
+		return 1, nil
+	case "all":
+		return total, nil
+	case "majority":
+		return total/2 + 1, nil
+	default:
+		n, err := strconv.Atoi(c.Policy)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid connectivity policy: %s", c.Policy)
+		}
+		return min(n, total), nil
+	}
+}
+
+type httpTarget struct {
+	url string
+}
+
+func (t *httpTarget) Check(ctx context.Context, network string) error {
+	dialer := &net.Dialer{}
+	client := resty.New().SetTransport(&http.Transport{
+		DialContext: func(ctx context.Context, _ string, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	})
+
+	resp, err := client.R().SetContext(ctx).Get(t.url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+func (t *httpTarget) String() string {
+	return t.url
+}
+
+type tcpTarget struct {
+	addr string
+}
+
+func (t *tcpTarget) Check(ctx context.Context, network string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, t.addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (t *tcpTarget) String() string {
+	return "tcp://" + t.addr
+}
+
+type dnsTarget struct {
+	server string
+	domain string
+}
+
+// Check resolves the domain with the server over udp on the network family
+func (t *dnsTarget) Check(ctx context.Context, network string) error {
+	udp := "udp4"
+	if network == "tcp6" {
+		udp = "udp6"
+	}
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, udp, t.server)
+		},
+	}
+	_, err := r.LookupHost(ctx, t.domain)
+	return err
+}
+
+func (t *dnsTarget) String() string {
+	return "dns://" + t.server + "/" + t.domain
+}
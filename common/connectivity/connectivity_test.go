@@ -0,0 +1,133 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeTarget struct {
+	err error
+}
+
+func (f *fakeTarget) Check(context.Context, string) error {
+	return f.err
+}
+
+func (f *fakeTarget) String() string {
+	return "fake"
+}
+
+// slowTarget succeeds after the delay unless the context is done
+type slowTarget struct {
+	delay time.Duration
+}
+
+func (s *slowTarget) Check(ctx context.Context, _ string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.delay):
+		return nil
+	}
+}
+
+func (s *slowTarget) String() string {
+	return "slow"
+}
+
+func TestChecker_Check(t *testing.T) {
+	up, down := &fakeTarget{}, &fakeTarget{err: errors.New("timeout")}
+
+	for _, tt := range []struct {
+		policy  string
+		targets []Target
+		ok      bool
+	}{
+		{"", []Target{down, up}, true},
+		{"any", []Target{down, down}, false},
+		{"all", []Target{up, down}, false},
+		{"majority", []Target{up, up, down}, true},
+		{"majority", []Target{up, down, down}, false},
+		{"2", []Target{up, down, up}, true},
+		{"5", []Target{up, up}, true},
+	} {
+		c := &Checker{Network: "tcp4", Targets: tt.targets, Policy: tt.policy, Timeout: time.Second}
+		if err := c.Check(context.Background()); (err == nil) != tt.ok {
+			t.Errorf("policy %q: unexpected result: %v", tt.policy, err)
+		}
+	}
+
+	// the zero timeout falls back to the default one
+	c := &Checker{Network: "tcp4", Targets: []Target{&slowTarget{delay: time.Millisecond * 50}}}
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("zero timeout: unexpected result: %v", err)
+	}
+
+	c = &Checker{Targets: []Target{up}, Policy: "most"}
+	if err := c.Check(context.Background()); err == nil {
+		t.Error("expected policy error")
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for s, want := range map[string]string{
+		"http://www.baidu.com":          "http://www.baidu.com",
+		"1.1.1.1:443":                   "tcp://1.1.1.1:443",
+		"tcp://[2606:4700::1111]:443":   "tcp://[2606:4700::1111]:443",
+		"dns://8.8.8.8/www.google.com":  "dns://8.8.8.8:53/www.google.com",
+		"dns://[2001:4860::8888]/a.com": "dns://[2001:4860::8888]:53/a.com",
+	} {
+		target, err := ParseTarget(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if target.String() != want {
+			t.Errorf("%s: expected %s, got %s", s, want, target)
+		}
+	}
+
+	for _, s := range []string{"1.1.1.1", "dns://8.8.8.8", "icmp://1.1.1.1"} {
+		if _, err := ParseTarget(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := (&httpTarget{url: srv.URL}).Check(ctx, "tcp4"); err != nil {
+		t.Error(err)
+	}
+	if err := (&httpTarget{url: srv.URL + "/404"}).Check(ctx, "tcp4"); err == nil {
+		t.Error("expected status error")
+	}
+	if err := (&tcpTarget{addr: srv.Listener.Addr().String()}).Check(ctx, "tcp4"); err != nil {
+		t.Error(err)
+	}
+
+	// a closed port
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	if err := (&tcpTarget{addr: addr}).Check(ctx, "tcp4"); err == nil {
+		t.Error("expected dial error")
+	}
+}
@@ -0,0 +1,258 @@
+package alidns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+const (
+	defaultEndpoint = "https://alidns.aliyuncs.com"
+	defaultLine     = "default"
+	apiVersion      = "2015-01-09"
+)
+
+// AliDNS Implementation, backed by the Aliyun RPC API
+type AliDNS struct {
+	accessKeyID     string
+	accessKeySecret string
+	line            string
+	ttl             string
+	client          *resty.Client
+}
+
+func New(c map[string]string) (*AliDNS, error) {
+	a := &AliDNS{
+		accessKeyID:     c[strings.ToLower("ALIDNS_ACCESS_KEY_ID")],
+		accessKeySecret: c[strings.ToLower("ALIDNS_ACCESS_KEY_SECRET")],
+		line:            c[strings.ToLower("ALIDNS_LINE")],
+		ttl:             c[strings.ToLower("ALIDNS_TTL")],
+	}
+	if a.accessKeyID == "" || a.accessKeySecret == "" {
+		return nil, errors.New("alidns access key id or access key secret is empty")
+	}
+	if a.line == "" {
+		a.line = defaultLine
+	}
+
+	endpoint := c[strings.ToLower("ALIDNS_ENDPOINT")]
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	a.client = resty.New().SetBaseURL(endpoint).SetTimeout(time.Second * 10)
+
+	return a, nil
+}
+
+// AddUpdateDomainRecords create or update IPv4/IPv6 records
+func (a *AliDNS) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	recordType, err := ddns.RecordType(network)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+	if ipAddr == "" {
+		return ddns.Unchanged, errors.New("IP address is nil")
+	}
+
+	return a.Reconcile(recordType, domain, []string{ipAddr})
+}
+
+// Reconcile converges the records of recordType on domain and the configured line to exactly ips
+func (a *AliDNS) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	records, err := a.getRecords(ctx, recordType, domain)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+
+	current := make([]ddns.Record, len(records))
+	for i := range records {
+		current[i] = ddns.Record{ID: records[i].RecordId, Value: records[i].Value}
+	}
+	updates, creates, deletes := ddns.Diff(current, ips)
+
+	for i, r := range updates {
+		params := a.recordParams(records[0].RR, recordType, r.Value)
+		params["RecordId"] = r.ID
+		if err := a.call(ctx, "UpdateDomainRecord", params, &recordResp{}); err != nil {
+			return ddns.ResultOf(updates[:i], nil, nil), fmt.Errorf("update record failure, Error: %w", err)
+		}
+	}
+	if len(creates) > 0 {
+		main := &mainDomainNameResp{}
+		if err := a.call(ctx, "GetMainDomainName", map[string]string{"InputString": domain}, main); err != nil {
+			return ddns.ResultOf(updates, nil, nil), err
+		}
+		rr := main.RR
+		if rr == "" {
+			rr = "@"
+		}
+
+		for i, ip := range creates {
+			params := a.recordParams(rr, recordType, ip)
+			params["DomainName"] = main.DomainName
+			if err := a.call(ctx, "AddDomainRecord", params, &recordResp{}); err != nil {
+				return ddns.ResultOf(updates, creates[:i], nil), fmt.Errorf("create record failure, Error: %w", err)
+			}
+		}
+	}
+	for i, r := range deletes {
+		if err := a.call(ctx, "DeleteDomainRecord", map[string]string{"RecordId": r.ID}, &recordResp{}); err != nil {
+			return ddns.ResultOf(updates, creates, deletes[:i]), fmt.Errorf("delete record failure, Error: %w", err)
+		}
+	}
+
+	return ddns.ResultOf(updates, creates, deletes), nil
+}
+
+func (a *AliDNS) recordParams(rr string, recordType string, ipAddr string) map[string]string {
+	params := map[string]string{
+		"RR":    rr,
+		"Type":  recordType,
+		"Value": ipAddr,
+		"Line":  a.line,
+	}
+	if a.ttl != "" {
+		params["TTL"] = a.ttl
+	}
+	return params
+}
+
+func (a *AliDNS) GetDomainRecords(recordType string, domain string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	records, err := a.getRecords(ctx, recordType, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[string]bool)
+	for i := range records {
+		domains[records[i].Value] = true
+	}
+	return domains, nil
+}
+
+// getRecords returns the records of domain on the configured line
+func (a *AliDNS) getRecords(ctx context.Context, recordType string, domain string) ([]record, error) {
+	rtn := &subDomainRecordsResp{}
+	if err := a.call(ctx, "DescribeSubDomainRecords", map[string]string{
+		"SubDomain": domain,
+		"Type":      recordType,
+		"Line":      a.line,
+		"PageSize":  "500",
+	}, rtn); err != nil {
+		return nil, err
+	}
+
+	return rtn.DomainRecords.Record, nil
+}
+
+// call signs and sends an RPC request, then decodes the response into result
+func (a *AliDNS) call(ctx context.Context, action string, params map[string]string, result any) error {
+	query := map[string]string{
+		"Action":           action,
+		"Format":           "JSON",
+		"Version":          apiVersion,
+		"AccessKeyId":      a.accessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   nonce(),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	query["Signature"] = a.sign("GET", query)
+
+	resp, err := a.client.R().SetContext(ctx).SetQueryParams(query).Get("/")
+	if err != nil {
+		return ddns.WrapNetError(err)
+	}
+
+	if resp.IsError() {
+		apiErr := &apiError{}
+		if err := json.Unmarshal(resp.Body(), apiErr); err != nil || apiErr.Code == "" {
+			return ddns.StatusError(resp.StatusCode(), resp.String())
+		}
+		return apiErr.wrap(resp.StatusCode())
+	}
+
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// sign computes the RPC style HMAC-SHA1 signature of the query
+func (a *AliDNS) sign(method string, query map[string]string) string {
+	h := hmac.New(sha1.New, []byte(a.accessKeySecret+"&"))
+	h.Write([]byte(stringToSign(method, query)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// stringToSign canonicalizes the query sorted by key, the signature itself is left out
+func stringToSign(method string, query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k != "Signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = percentEncode(k) + "=" + percentEncode(query[k])
+	}
+	return method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("[AliDNS] %s: %s", e.Code, e.Message)
+}
+
+// wrap maps the api error code to the ddns errors, unknown codes fall back to the http status
+func (e *apiError) wrap(status int) error {
+	switch {
+	case strings.HasPrefix(e.Code, "InvalidAccessKeyId"), e.Code == "SignatureDoesNotMatch", strings.HasPrefix(e.Code, "Forbidden"):
+		return fmt.Errorf("%w: %w", ddns.ErrAuth, e)
+	case strings.HasPrefix(e.Code, "Throttling"):
+		return fmt.Errorf("%w: %w", ddns.ErrRateLimited, e)
+	case strings.HasPrefix(e.Code, "InternalError"), e.Code == "ServiceUnavailable", e.Code == "LastOperationNotFinished":
+		return fmt.Errorf("%w: %w", ddns.ErrTransient, e)
+	case strings.HasPrefix(e.Code, "InvalidDomainName"), e.Code == "IncorrectDomainUser":
+		return fmt.Errorf("%w: %w", ddns.ErrZoneNotFound, e)
+	case status >= 500:
+		return fmt.Errorf("%w: %w", ddns.ErrTransient, e)
+	default:
+		return e
+	}
+}
+
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
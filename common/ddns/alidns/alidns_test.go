@@ -0,0 +1,195 @@
+package alidns
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+type fakeAliDNS struct {
+	t       *testing.T
+	secret  string
+	records []record
+	nextID  int
+}
+
+func (f *fakeAliDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := map[string]string{}
+	for k := range r.URL.Query() {
+		query[k] = r.URL.Query().Get(k)
+	}
+	if query["Signature"] != (&AliDNS{accessKeySecret: f.secret}).sign("GET", query) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(apiError{Code: "SignatureDoesNotMatch", Message: "bad signature"})
+		return
+	}
+
+	switch query["Action"] {
+	case "DescribeSubDomainRecords":
+		rtn := &subDomainRecordsResp{}
+		for _, rec := range f.records {
+			if rec.RR+"."+rec.DomainName == query["SubDomain"] && rec.Type == query["Type"] && rec.Line == query["Line"] {
+				rtn.DomainRecords.Record = append(rtn.DomainRecords.Record, rec)
+			}
+		}
+		rtn.TotalCount = len(rtn.DomainRecords.Record)
+		_ = json.NewEncoder(w).Encode(rtn)
+	case "GetMainDomainName":
+		rr, _ := strings.CutSuffix(query["InputString"], ".example.com")
+		_ = json.NewEncoder(w).Encode(mainDomainNameResp{DomainName: "example.com", RR: rr})
+	case "AddDomainRecord":
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.records = append(f.records, record{
+			RecordId:   id,
+			RR:         query["RR"],
+			DomainName: query["DomainName"],
+			Type:       query["Type"],
+			Value:      query["Value"],
+			Line:       query["Line"],
+		})
+		_ = json.NewEncoder(w).Encode(recordResp{RecordId: id})
+	case "UpdateDomainRecord":
+		for i := range f.records {
+			if f.records[i].RecordId == query["RecordId"] {
+				f.records[i].Value = query["Value"]
+			}
+		}
+		_ = json.NewEncoder(w).Encode(recordResp{RecordId: query["RecordId"]})
+	default:
+		f.t.Errorf("unexpected action %s", query["Action"])
+	}
+}
+
+func newTestAliDNS(t *testing.T, line string) (*AliDNS, *fakeAliDNS) {
+	f := &fakeAliDNS{t: t, secret: "secret"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	a, err := New(map[string]string{
+		strings.ToLower("ALIDNS_ACCESS_KEY_ID"):     "id",
+		strings.ToLower("ALIDNS_ACCESS_KEY_SECRET"): "secret",
+		strings.ToLower("ALIDNS_LINE"):              line,
+		strings.ToLower("ALIDNS_ENDPOINT"):          srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, f
+}
+
+func TestAliDNS_AddUpdateDomainRecords(t *testing.T) {
+	a, f := newTestAliDNS(t, "")
+
+	if _, err := a.AddUpdateDomainRecords("tcp4", "hk1.example.com", "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.records) != 1 || f.records[0].RR != "hk1" || f.records[0].Line != defaultLine {
+		t.Fatalf("unexpected records: %+v", f.records)
+	}
+
+	if res, err := a.AddUpdateDomainRecords("tcp4", "hk1.example.com", "1.2.3.4"); err != nil || res != ddns.Unchanged {
+		t.Errorf("expected unchanged: %v %v", res, err)
+	}
+
+	if _, err := a.AddUpdateDomainRecords("tcp4", "hk1.example.com", "5.6.7.8"); err != nil {
+		t.Fatal(err)
+	}
+	ips, err := a.GetDomainRecords("A", "hk1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["5.6.7.8"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+}
+
+func TestAliDNS_Line(t *testing.T) {
+	def, f := newTestAliDNS(t, "")
+	if _, err := def.AddUpdateDomainRecords("tcp4", "hk1.example.com", "1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second client on the china telecom line shares the same zone
+	telecom := &AliDNS{
+		accessKeyID:     def.accessKeyID,
+		accessKeySecret: def.accessKeySecret,
+		line:            "telecom",
+		client:          def.client,
+	}
+	if _, err := telecom.AddUpdateDomainRecords("tcp4", "hk1.example.com", "2.2.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.records) != 2 || f.records[0].Value != "1.1.1.1" || f.records[1].Line != "telecom" {
+		t.Fatalf("unexpected records: %+v", f.records)
+	}
+
+	ips, err := telecom.GetDomainRecords("A", "hk1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["2.2.2.2"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+}
+
+func TestAliDNS_Error(t *testing.T) {
+	a, _ := newTestAliDNS(t, "")
+	a.accessKeySecret = "wrong"
+
+	_, err := a.GetDomainRecords("A", "hk1.example.com")
+	apiErr, ok := errors.AsType[*apiError](err)
+	if !ok || apiErr.Code != "SignatureDoesNotMatch" || !errors.Is(err, ddns.ErrAuth) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestAliDNS_sign checks the signature against the example of the Alibaba Cloud RPC signature document
+func TestAliDNS_sign(t *testing.T) {
+	query := map[string]string{
+		"Timestamp":        "2016-02-23T12:46:24Z",
+		"Format":           "XML",
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeRegions",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"Version":          "2014-05-26",
+		"SignatureVersion": "1.0",
+	}
+
+	want := "GET&%2F&AccessKeyId%3Dtestid%26Action%3DDescribeRegions%26Format%3DXML%26SignatureMethod%3DHMAC-SHA1" +
+		"%26SignatureNonce%3D3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf%26SignatureVersion%3D1.0" +
+		"%26Timestamp%3D2016-02-23T12%253A46%253A24Z%26Version%3D2014-05-26"
+	if got := stringToSign("GET", query); got != want {
+		t.Errorf("unexpected string to sign: %s", got)
+	}
+
+	// the key is the secret with the "&" suffix
+	if got := (&AliDNS{accessKeySecret: "testsecret"}).sign("GET", query); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("unexpected signature: %s", got)
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	for s, want := range map[string]string{
+		"a b":         "a%20b",
+		"a+b":         "a%2Bb",
+		"a*b":         "a%2Ab",
+		"a~b":         "a~b",
+		"a-_.b":       "a-_.b",
+		"1.1.1.1/32":  "1.1.1.1%2F32",
+		"默认":          "%E9%BB%98%E8%AE%A4",
+		"key=v&k2=v2": "key%3Dv%26k2%3Dv2",
+	} {
+		if got := percentEncode(s); got != want {
+			t.Errorf("%s: expected %s, got %s", s, want, got)
+		}
+	}
+}
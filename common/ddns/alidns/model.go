@@ -0,0 +1,33 @@
+package alidns
+
+type apiError struct {
+	RequestId string `json:"RequestId"`
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+}
+
+type record struct {
+	RecordId   string `json:"RecordId"`
+	RR         string `json:"RR"`
+	DomainName string `json:"DomainName"`
+	Type       string `json:"Type"`
+	Value      string `json:"Value"`
+	Line       string `json:"Line"`
+	TTL        int64  `json:"TTL"`
+}
+
+type subDomainRecordsResp struct {
+	TotalCount    int `json:"TotalCount"`
+	DomainRecords struct {
+		Record []record `json:"Record"`
+	} `json:"DomainRecords"`
+}
+
+type mainDomainNameResp struct {
+	DomainName string `json:"DomainName"`
+	RR         string `json:"RR"`
+}
+
+type recordResp struct {
+	RecordId string `json:"RecordId"`
+}
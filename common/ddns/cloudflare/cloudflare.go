@@ -3,22 +3,65 @@ package cloudflare
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
 	"golang.org/x/net/context"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
 )
 
 // Cloudflare Implementation
 type Cloudflare struct {
-	client *cloudflare.API
+	client  *cloudflare.API
+	zoneID  string
+	ttl     int
+	proxied *bool
+	comment string
+
+	mu    sync.Mutex
+	zones map[string]string // zone name to zone ID
+}
+
+// Proxied reports whether the records are proxied by cloudflare
+func (cf *Cloudflare) Proxied() bool {
+	return cf.proxied != nil && *cf.proxied
 }
 
 func New(c map[string]string) (*Cloudflare, error) {
-	cf := new(Cloudflare)
+	cf := &Cloudflare{
+		zoneID:  c[strings.ToLower("CLOUDFLARE_ZONE_ID")],
+		comment: c[strings.ToLower("CLOUDFLARE_COMMENT")],
+	}
+
+	if ttl := c[strings.ToLower("CLOUDFLARE_TTL")]; ttl != "" {
+		v, err := strconv.Atoi(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloudflare ttl: %s", ttl)
+		}
+		cf.ttl = v
+	}
+	if proxied := c[strings.ToLower("CLOUDFLARE_PROXIED")]; proxied != "" {
+		v, err := strconv.ParseBool(proxied)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloudflare proxied: %s", proxied)
+		}
+		cf.proxied = &v
+	}
 
-	client, err := cloudflare.New(c[strings.ToLower("CLOUDFLARE_API_KEY")], c[strings.ToLower("CLOUDFLARE_EMAIL")])
+	var (
+		client *cloudflare.API
+		err    error
+	)
+	// prefer the scoped api token over the legacy global api key
+	if token := c[strings.ToLower("CLOUDFLARE_API_TOKEN")]; token != "" {
+		client, err = cloudflare.NewWithAPIToken(token)
+	} else {
+		client, err = cloudflare.New(c[strings.ToLower("CLOUDFLARE_API_KEY")], c[strings.ToLower("CLOUDFLARE_EMAIL")])
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -28,84 +71,124 @@ func New(c map[string]string) (*Cloudflare, error) {
 }
 
 // AddUpdateDomainRecords create or update IPv4/IPv6 records
-func (cf *Cloudflare) AddUpdateDomainRecords(network string, domain string, ipAddr string) error {
-	switch network {
-	case "tcp4":
-		return cf.addUpdateDomainRecords("A", domain, ipAddr)
-	case "tcp6":
-		return cf.addUpdateDomainRecords("AAAA", domain, ipAddr)
-	default:
-		return errors.New("not support network")
+func (cf *Cloudflare) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	recordType, err := ddns.RecordType(network)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+	if ipAddr == "" {
+		return ddns.Unchanged, errors.New("IP address is nil")
 	}
+
+	return cf.Reconcile(recordType, domain, []string{ipAddr})
 }
 
-func (cf *Cloudflare) addUpdateDomainRecords(recordType string, domain string, ipAddr string) error {
+// Reconcile converges the records of recordType on domain to exactly ips
+func (cf *Cloudflare) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	if ipAddr == "" {
-		return errors.New("IP address is nil")
-	}
-
 	zoneID, records, err := cf.getRecords(ctx, recordType, domain)
 	if err != nil {
-		return err
-	}
-
-	if len(records) > 0 {
-		for i := range records {
-			if records[i].Content == ipAddr {
-				return fmt.Errorf("ip %s have no change", ipAddr)
-			}
-			_, err = cf.client.UpdateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.UpdateDNSRecordParams{
-				Type:    recordType,
-				ID:      records[i].ID,
-				Content: ipAddr,
-			})
-			if err != nil {
-				return fmt.Errorf("update record failure, Error: %s", err)
-			}
+		return ddns.Unchanged, err
+	}
+
+	current := make([]ddns.Record, len(records))
+	for i := range records {
+		current[i] = ddns.Record{ID: records[i].ID, Value: records[i].Content}
+	}
+	updates, creates, deletes := ddns.Diff(current, ips)
+	rc := cloudflare.ZoneIdentifier(zoneID)
+
+	for i, r := range updates {
+		params := cloudflare.UpdateDNSRecordParams{
+			Type:    recordType,
+			ID:      r.ID,
+			Content: r.Value,
+			TTL:     cf.ttl,
+			Proxied: cf.proxied,
 		}
-	} else {
-		_, err := cf.client.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
+		if cf.comment != "" {
+			params.Comment = &cf.comment
+		}
+		if _, err := cf.client.UpdateDNSRecord(ctx, rc, params); err != nil {
+			return ddns.ResultOf(updates[:i], nil, nil), fmt.Errorf("update record failure, Error: %w", wrapError(err))
+		}
+	}
+	for i, ip := range creates {
+		if _, err := cf.client.CreateDNSRecord(ctx, rc, cloudflare.CreateDNSRecordParams{
 			Type:    recordType,
 			Name:    domain,
-			Content: ipAddr,
-		})
-		if err != nil {
-			return fmt.Errorf("create record failure, Error: %s", err)
+			Content: ip,
+			TTL:     cf.ttl,
+			Proxied: cf.proxied,
+			Comment: cf.comment,
+		}); err != nil {
+			return ddns.ResultOf(updates, creates[:i], nil), fmt.Errorf("create record failure, Error: %w", wrapError(err))
+		}
+	}
+	for i, r := range deletes {
+		if err := cf.client.DeleteDNSRecord(ctx, rc, r.ID); err != nil {
+			return ddns.ResultOf(updates, creates, deletes[:i]), fmt.Errorf("delete record failure, Error: %w", wrapError(err))
 		}
 	}
-	return nil
+
+	return ddns.ResultOf(updates, creates, deletes), nil
 }
 
 func (cf *Cloudflare) getRecords(ctx context.Context, recordType string, domain string) (string, []cloudflare.DNSRecord, error) {
-	zones, err := cf.client.ListZones(ctx)
+	zoneID, err := cf.getZoneID(ctx, domain)
 	if err != nil {
 		return "", nil, err
 	}
 
-	// Get zone
-	zoneID := ""
-	for i := range zones {
-		if strings.Contains(domain, zones[i].Name) {
-			zoneID = zones[i].ID
-		}
-	}
-	if zoneID == "" {
-		return "", nil, errors.New("cannot find a valid zone")
-	}
-
 	records, _, err := cf.client.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
 		Type: recordType,
 		Name: domain,
 	})
 	if err != nil {
-		return "", nil, err
+		return "", nil, wrapError(err)
 	}
 	return zoneID, records, nil
 }
 
+// getZoneID returns the pinned zone ID, or the ID of the longest zone that domain belongs to.
+// The zone list is cached and only reloaded when no zone matches.
+func (cf *Cloudflare) getZoneID(ctx context.Context, domain string) (string, error) {
+	if cf.zoneID != "" {
+		return cf.zoneID, nil
+	}
+
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	if id, ok := cf.zones[cf.matchZone(domain)]; ok {
+		return id, nil
+	}
+
+	zones, err := cf.client.ListZones(ctx)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	cf.zones = make(map[string]string, len(zones))
+	for i := range zones {
+		cf.zones[zones[i].Name] = zones[i].ID
+	}
+
+	if id, ok := cf.zones[cf.matchZone(domain)]; ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("%w: cannot find a valid zone for %s", ddns.ErrZoneNotFound, domain)
+}
+
+func (cf *Cloudflare) matchZone(domain string) string {
+	names := make([]string, 0, len(cf.zones))
+	for name := range cf.zones {
+		names = append(names, name)
+	}
+	return ddns.MatchZone(domain, names)
+}
+
 func (cf *Cloudflare) GetDomainRecords(recordType string, domain string) (domains map[string]bool, err error) {
 	domains = make(map[string]bool)
 	ctx := context.Background()
@@ -119,3 +202,20 @@ func (cf *Cloudflare) GetDomainRecords(recordType string, domain string) (domain
 	}
 	return domains, nil
 }
+
+// wrapError maps the cloudflare api errors to the ddns errors
+func wrapError(err error) error {
+	if _, ok := errors.AsType[*cloudflare.AuthenticationError](err); ok {
+		return fmt.Errorf("%w: %w", ddns.ErrAuth, err)
+	}
+	if _, ok := errors.AsType[*cloudflare.AuthorizationError](err); ok {
+		return fmt.Errorf("%w: %w", ddns.ErrAuth, err)
+	}
+	if _, ok := errors.AsType[*cloudflare.RatelimitError](err); ok {
+		return fmt.Errorf("%w: %w", ddns.ErrRateLimited, err)
+	}
+	if _, ok := errors.AsType[*cloudflare.ServiceError](err); ok {
+		return fmt.Errorf("%w: %w", ddns.ErrTransient, err)
+	}
+	return ddns.WrapNetError(err)
+}
@@ -0,0 +1,205 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+type fakeCloudflare struct {
+	t          *testing.T
+	zones      []cloudflare.Zone
+	records    map[string][]cloudflare.DNSRecord // zone ID to records
+	listZones  atomic.Int32
+	nextID     int
+	failDelete bool
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []map[string]any{{"code": 9109, "message": "Invalid access token"}}})
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "zones":
+		f.listZones.Add(1)
+		writeResult(w, f.zones)
+	case r.Method == http.MethodGet && len(path) == 3:
+		var list []cloudflare.DNSRecord
+		for _, rec := range f.records[path[1]] {
+			if rec.Type == r.URL.Query().Get("type") && rec.Name == r.URL.Query().Get("name") {
+				list = append(list, rec)
+			}
+		}
+		writeResult(w, list)
+	case r.Method == http.MethodPost && len(path) == 3:
+		rec := cloudflare.DNSRecord{}
+		_ = json.NewDecoder(r.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = string(rune('a' + f.nextID))
+		f.records[path[1]] = append(f.records[path[1]], rec)
+		writeResult(w, rec)
+	case r.Method == http.MethodPatch && len(path) == 4:
+		params := cloudflare.UpdateDNSRecordParams{}
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		for i, rec := range f.records[path[1]] {
+			if rec.ID == path[3] {
+				f.records[path[1]][i].Content = params.Content
+				writeResult(w, f.records[path[1]][i])
+			}
+		}
+	case r.Method == http.MethodDelete && len(path) == 4 && f.failDelete:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []map[string]any{{"code": 81044, "message": "Record does not exist"}}})
+	case r.Method == http.MethodDelete && len(path) == 4:
+		f.records[path[1]] = slices.DeleteFunc(f.records[path[1]], func(rec cloudflare.DNSRecord) bool {
+			return rec.ID == path[3]
+		})
+		writeResult(w, map[string]string{"id": path[3]})
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	}
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"success":     true,
+		"result":      result,
+		"result_info": map[string]int{"page": 1, "per_page": 50, "total_pages": 1},
+	})
+}
+
+func newTestCloudflare(t *testing.T, c map[string]string) (*Cloudflare, *fakeCloudflare) {
+	f := &fakeCloudflare{
+		t: t,
+		zones: []cloudflare.Zone{
+			{ID: "z1", Name: "example.com"},
+			{ID: "z2", Name: "example.com.cn"},
+			{ID: "z3", Name: "sub.example.com"},
+		},
+		records: map[string][]cloudflare.DNSRecord{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	c[strings.ToLower("CLOUDFLARE_API_TOKEN")] = "token"
+	cf, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.client.BaseURL = srv.URL
+	return cf, f
+}
+
+func TestCloudflare_ZoneMatching(t *testing.T) {
+	cf, f := newTestCloudflare(t, map[string]string{})
+
+	tests := map[string]string{
+		"hk1.example.com":     "z1",
+		"hk1.example.com.cn":  "z2",
+		"hk1.sub.example.com": "z3",
+		"example.com":         "z1",
+	}
+	for domain, want := range tests {
+		if got, err := cf.getZoneID(t.Context(), domain); err != nil || got != want {
+			t.Errorf("%s: got %s %v, want %s", domain, got, err, want)
+		}
+	}
+	if _, err := cf.getZoneID(t.Context(), "hk1.notexample.com"); err == nil {
+		t.Error("expected zone error for notexample.com")
+	}
+
+	// the zone list is only reloaded for domains without a cached zone
+	if n := f.listZones.Load(); n != 2 {
+		t.Errorf("unexpected zone list count: %d", n)
+	}
+}
+
+func TestCloudflare_AddUpdateDomainRecords(t *testing.T) {
+	cf, f := newTestCloudflare(t, map[string]string{
+		strings.ToLower("CLOUDFLARE_ZONE_ID"): "pinned",
+		strings.ToLower("CLOUDFLARE_TTL"):     "60",
+		strings.ToLower("CLOUDFLARE_PROXIED"): "false",
+		strings.ToLower("CLOUDFLARE_COMMENT"): "LightsailMon",
+	})
+
+	if _, err := cf.AddUpdateDomainRecords("tcp4", "hk1.example.com", "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	recs := f.records["pinned"]
+	if len(recs) != 1 || recs[0].TTL != 60 || recs[0].Proxied == nil || *recs[0].Proxied || recs[0].Comment != "LightsailMon" {
+		t.Fatalf("unexpected records: %+v", recs)
+	}
+
+	if _, err := cf.AddUpdateDomainRecords("tcp4", "hk1.example.com", "5.6.7.8"); err != nil {
+		t.Fatal(err)
+	}
+	ips, err := cf.GetDomainRecords("A", "hk1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["5.6.7.8"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+
+	if n := f.listZones.Load(); n != 0 {
+		t.Errorf("zones should not be listed with a pinned zone id: %d", n)
+	}
+}
+
+func TestCloudflare_Reconcile(t *testing.T) {
+	cf, f := newTestCloudflare(t, map[string]string{})
+	f.records["z1"] = []cloudflare.DNSRecord{
+		{ID: "r1", Type: "A", Name: "hk1.example.com", Content: "1.1.1.1"},
+		{ID: "r2", Type: "A", Name: "hk1.example.com", Content: "1.1.1.1"},
+		{ID: "r3", Type: "A", Name: "hk1.example.com", Content: "2.2.2.2"},
+		{ID: "r4", Type: "AAAA", Name: "hk1.example.com", Content: "2001:db8::1"},
+	}
+
+	res, err := cf.Reconcile("A", "hk1.example.com", []string{"1.1.1.1", "3.3.3.3"})
+	if err != nil || res != ddns.Updated {
+		t.Fatal(res, err)
+	}
+	ips, _ := cf.GetDomainRecords("A", "hk1.example.com")
+	if len(ips) != 2 || !ips["1.1.1.1"] || !ips["3.3.3.3"] || len(f.records["z1"]) != 3 {
+		t.Errorf("unexpected records: %+v", f.records["z1"])
+	}
+
+	if res, err := cf.Reconcile("A", "hk1.example.com", []string{"3.3.3.3", "1.1.1.1"}); err != nil || res.Changed() {
+		t.Errorf("expected no change: %v %v", res, err)
+	}
+
+	// drop the AAAA records when the node leaves tcp6
+	if res, err := cf.Reconcile("AAAA", "hk1.example.com", nil); err != nil || res != ddns.Deleted {
+		t.Fatal(res, err)
+	}
+	if ips, _ := cf.GetDomainRecords("AAAA", "hk1.example.com"); len(ips) != 0 {
+		t.Errorf("unexpected AAAA records: %v", ips)
+	}
+}
+
+func TestCloudflare_ReconcilePartial(t *testing.T) {
+	cf, f := newTestCloudflare(t, map[string]string{})
+	f.records["z1"] = []cloudflare.DNSRecord{
+		{ID: "r1", Type: "A", Name: "hk1.example.com", Content: "1.1.1.1"},
+		{ID: "r2", Type: "A", Name: "hk1.example.com", Content: "2.2.2.2"},
+	}
+	f.failDelete = true
+
+	// the update is reported along with the delete failure
+	res, err := cf.Reconcile("A", "hk1.example.com", []string{"3.3.3.3"})
+	if err == nil || res != ddns.Updated {
+		t.Errorf("expected the partial update: %v %v", res, err)
+	}
+}
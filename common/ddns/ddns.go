@@ -1,6 +1,183 @@
 package ddns
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Errors that providers wrap, so that callers can tell permanent failures from temporary ones
+var (
+	ErrAuth         = errors.New("ddns authentication failed")
+	ErrZoneNotFound = errors.New("ddns zone not found")
+	ErrRateLimited  = errors.New("ddns rate limited")
+	ErrTransient    = errors.New("ddns transient failure")
+	ErrNotSupported = errors.New("not supported by the ddns provider")
+)
+
+// Result tells what a record change did
+type Result int
+
+const (
+	Unchanged Result = iota
+	Created
+	Updated
+	Deleted
+)
+
 type Client interface {
-	AddUpdateDomainRecords(network string, ipAddr string, domain string) error
+	AddUpdateDomainRecords(network string, domain string, ipAddr string) (Result, error)
 	GetDomainRecords(recordType string, domain string) (domains map[string]bool, err error)
+	// Reconcile converges the records of recordType on domain to exactly ips, on error the result still reports the
+	// changes made before it
+	Reconcile(recordType string, domain string, ips []string) (Result, error)
+}
+
+// Proxier is implemented by the providers that can proxy the records, a proxied record resolves to the proxy addresses
+type Proxier interface {
+	Proxied() bool
+}
+
+// IsProxied reports whether the records of cli resolve to a proxy, so that they can not be verified by resolving
+func IsProxied(cli Client) bool {
+	p, ok := cli.(Proxier)
+	return ok && p.Proxied()
+}
+
+func (r Result) String() string {
+	switch r {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Deleted:
+		return "deleted"
+	default:
+		return "unchanged"
+	}
+}
+
+// Changed reports whether any record was changed
+func (r Result) Changed() bool {
+	return r != Unchanged
+}
+
+// IsTemporary reports whether err may go away on retry
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrTransient) || errors.Is(err, ErrRateLimited)
+}
+
+// RecordType returns the record type that serves network
+func RecordType(network string) (string, error) {
+	switch network {
+	case "tcp4":
+		return "A", nil
+	case "tcp6":
+		return "AAAA", nil
+	default:
+		return "", errors.New("not support network")
+	}
+}
+
+// WrapNetError marks network and timeout errors as transient
+func WrapNetError(err error) error {
+	if _, ok := errors.AsType[net.Error](err); ok || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTransient, err)
+	}
+	return err
+}
+
+// StatusError maps a failed HTTP status to the matching error
+func StatusError(status int, body string) error {
+	err := fmt.Errorf("unexpected status %d: %s", status, body)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrAuth, err)
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	case status >= 500:
+		return fmt.Errorf("%w: %w", ErrTransient, err)
+	default:
+		return err
+	}
+}
+
+// Record is an existing provider record
+type Record struct {
+	ID    string
+	Value string
+}
+
+// Diff plans the changes that turn records into exactly ips. Stale records are reused for missing
+// addresses before new records are created, and the remaining stale or duplicate records are deleted.
+func Diff(records []Record, ips []string) (updates []Record, creates []string, deletes []Record) {
+	desired := make(map[string]bool)
+	var missing []string
+	for _, ip := range ips {
+		ip = normalize(ip)
+		if !desired[ip] {
+			desired[ip] = true
+			missing = append(missing, ip)
+		}
+	}
+
+	kept := make(map[string]bool)
+	var stale []Record
+	for _, r := range records {
+		v := normalize(r.Value)
+		if desired[v] && !kept[v] {
+			kept[v] = true
+			continue
+		}
+		stale = append(stale, r)
+	}
+	missing = slices.DeleteFunc(missing, func(ip string) bool { return kept[ip] })
+
+	for len(stale) > 0 && len(missing) > 0 {
+		updates = append(updates, Record{ID: stale[0].ID, Value: missing[0]})
+		stale, missing = stale[1:], missing[1:]
+	}
+
+	return updates, missing, stale
+}
+
+// ResultOf summarizes the changes planned by Diff
+func ResultOf(updates []Record, creates []string, deletes []Record) Result {
+	switch {
+	case len(updates) > 0 || len(creates) > 0 && len(deletes) > 0:
+		return Updated
+	case len(creates) > 0:
+		return Created
+	case len(deletes) > 0:
+		return Deleted
+	default:
+		return Unchanged
+	}
+}
+
+// MatchZone returns the longest zone that domain belongs to, zones only match on whole labels
+func MatchZone(domain string, zones []string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	zone := ""
+	for _, z := range zones {
+		z = strings.TrimSuffix(strings.ToLower(z), ".")
+		if (domain == z || strings.HasSuffix(domain, "."+z)) && len(z) > len(zone) {
+			zone = z
+		}
+	}
+	return zone
+}
+
+// normalize returns the canonical form of an IP address, so that differently written IPv6 addresses compare equal
+func normalize(ip string) string {
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		return addr.String()
+	}
+	return ip
 }
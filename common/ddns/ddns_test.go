@@ -1,23 +1,169 @@
 package ddns
 
 import (
-	"strings"
+	"context"
+	"net"
+	"net/netip"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/Septrum101/lightsailMon/common/ddns/google"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
-func TestGoogle(t *testing.T) {
-	g, err := google.New(map[string]string{
-		strings.ToLower("GOOGLEDOMAIN_USERNAME"): "username",
-		strings.ToLower("GOOGLEDOMAIN_PASSWORD"): "password",
-	}, "subdomain.yourdomain.com")
+// fakeZone is a set of records that can be changed while it is served
+type fakeZone struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (z *fakeZone) set(name string, ips ...string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.records[name] = ips
+}
+
+func (z *fakeZone) get(name string) ([]string, bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	ips, ok := z.records[name]
+	return ips, ok
+}
+
+// serveDNS answers the A/AAAA queries of a fake zone on a local udp port
+func serveDNS(t *testing.T, zone *fakeZone) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+			q := msg.Questions[0]
+			msg.Header.Response = true
+			msg.Header.Authoritative = true
+
+			ips, ok := zone.get(q.Name.String())
+			if !ok {
+				msg.Header.RCode = dnsmessage.RCodeNameError
+			}
+			for _, ip := range ips {
+				a := netip.MustParseAddr(ip)
+				hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+				switch {
+				case q.Type == dnsmessage.TypeA && a.Is4():
+					msg.Answers = append(msg.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: a.As4()}})
+				case q.Type == dnsmessage.TypeAAAA && a.Is6():
+					msg.Answers = append(msg.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: a.As16()}})
+				}
+			}
+
+			if b, err := msg.Pack(); err == nil {
+				_, _ = conn.WriteTo(b, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
 
-	err = g.AddUpdateDomainRecords("tcp4", "1.2.3.4")
+func TestResolve(t *testing.T) {
+	ns := serveDNS(t, &fakeZone{records: map[string][]string{
+		"node1.test.com.": {"1.2.3.4", "5.6.7.8", "2001:db8::1"},
+	}})
+
+	ips, err := Resolve(context.Background(), ns, "A", "node1.test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || !ips["1.2.3.4"] || !ips["5.6.7.8"] {
+		t.Errorf("unexpected A records: %v", ips)
+	}
+
+	ips, err = Resolve(context.Background(), ns, "AAAA", "node1.test.com")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["2001:db8::1"] {
+		t.Errorf("unexpected AAAA records: %v", ips)
+	}
+
+	ips, err = Resolve(context.Background(), ns, "A", "node2.test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 0 {
+		t.Errorf("unexpected records of missing domain: %v", ips)
+	}
+}
+
+func TestWaitPropagation(t *testing.T) {
+	zone := &fakeZone{records: map[string][]string{"node1.test.com.": {"1.2.3.4"}}}
+	ns1, ns2 := serveDNS(t, zone), serveDNS(t, zone)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := WaitPropagation(ctx, []string{ns1, ns2}, "A", "node1.test.com", "1.2.3.4", time.Millisecond*10); err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(time.Millisecond*100, func() { zone.set("node1.test.com.", "5.6.7.8") })
+	elapsed, err := WaitPropagation(ctx, []string{ns1, ns2}, "A", "node1.test.com", "5.6.7.8", time.Millisecond*10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed < time.Millisecond*100 {
+		t.Errorf("propagated too early: %s", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := WaitPropagation(ctx, []string{ns1}, "A", "node1.test.com", "9.9.9.9", time.Millisecond*10); err == nil {
+		t.Error("expected propagation timeout")
+	}
+}
+
+func TestNameservers(t *testing.T) {
+	ns, err := Nameservers(context.Background(), "1.1.1.1:53", "node1.test.com")
+	if err != nil || len(ns) != 1 || ns[0] != "1.1.1.1:53" {
+		t.Errorf("configured nameserver should be used: %v %v", ns, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	records := []Record{
+		{ID: "1", Value: "1.1.1.1"},
+		{ID: "2", Value: "1.1.1.1"},
+		{ID: "3", Value: "2.2.2.2"},
+		{ID: "4", Value: "2001:db8:0:0::1"},
+	}
+
+	updates, creates, deletes := Diff(records, []string{"1.1.1.1", "3.3.3.3", "4.4.4.4", "2001:db8::1"})
+	if len(updates) != 2 || updates[0] != (Record{ID: "2", Value: "3.3.3.3"}) || updates[1] != (Record{ID: "3", Value: "4.4.4.4"}) {
+		t.Errorf("unexpected updates: %v", updates)
+	}
+	if len(creates) != 0 || len(deletes) != 0 {
+		t.Errorf("unexpected creates %v or deletes %v", creates, deletes)
+	}
+
+	updates, creates, deletes = Diff(records, []string{"1.1.1.1"})
+	if len(updates) != 0 || len(creates) != 0 || len(deletes) != 3 {
+		t.Errorf("unexpected diff: %v %v %v", updates, creates, deletes)
+	}
+
+	updates, creates, deletes = Diff(nil, []string{"1.1.1.1", "1.1.1.1"})
+	if len(updates) != 0 || len(creates) != 1 || len(deletes) != 0 {
+		t.Errorf("unexpected diff: %v %v %v", updates, creates, deletes)
 	}
 }
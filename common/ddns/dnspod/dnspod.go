@@ -0,0 +1,301 @@
+package dnspod
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+const (
+	defaultEndpoint = "https://dnspod.tencentcloudapi.com"
+	defaultLine     = "默认"
+	apiVersion      = "2021-03-23"
+	service         = "dnspod"
+	algorithm       = "TC3-HMAC-SHA256"
+)
+
+// DNSPod Implementation, backed by the Tencent Cloud API 3.0
+type DNSPod struct {
+	secretID  string
+	secretKey string
+	line      string
+	ttl       uint64
+	host      string
+	service   string // the signed service, always dnspod but for the signature tests
+	client    *resty.Client
+}
+
+func New(c map[string]string) (*DNSPod, error) {
+	d := &DNSPod{
+		secretID:  c[strings.ToLower("DNSPOD_SECRET_ID")],
+		secretKey: c[strings.ToLower("DNSPOD_SECRET_KEY")],
+		line:      c[strings.ToLower("DNSPOD_LINE")],
+		service:   service,
+	}
+	if d.secretID == "" || d.secretKey == "" {
+		return nil, errors.New("dnspod secret id or secret key is empty")
+	}
+	if d.line == "" {
+		d.line = defaultLine
+	}
+	if ttl := c[strings.ToLower("DNSPOD_TTL")]; ttl != "" {
+		v, err := strconv.ParseUint(ttl, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dnspod ttl: %s", ttl)
+		}
+		d.ttl = v
+	}
+
+	endpoint := c[strings.ToLower("DNSPOD_ENDPOINT")]
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	d.host = u.Host
+	d.client = resty.New().SetBaseURL(endpoint).SetTimeout(time.Second * 10)
+
+	return d, nil
+}
+
+// AddUpdateDomainRecords create or update IPv4/IPv6 records
+func (d *DNSPod) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	recordType, err := ddns.RecordType(network)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+	if ipAddr == "" {
+		return ddns.Unchanged, errors.New("IP address is nil")
+	}
+
+	return d.Reconcile(recordType, domain, []string{ipAddr})
+}
+
+// Reconcile converges the records of recordType on domain and the configured line to exactly ips
+func (d *DNSPod) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	zone, sub, records, err := d.getRecords(ctx, recordType, domain)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+
+	current := make([]ddns.Record, len(records))
+	for i := range records {
+		current[i] = ddns.Record{ID: strconv.FormatUint(records[i].RecordId, 10), Value: records[i].Value}
+	}
+	updates, creates, deletes := ddns.Diff(current, ips)
+
+	for i, r := range updates {
+		id, _ := strconv.ParseUint(r.ID, 10, 64)
+		params := d.recordParams(zone, sub, recordType, r.Value)
+		params["RecordId"] = id
+		if err := d.call(ctx, "ModifyRecord", params, &recordResp{}); err != nil {
+			return ddns.ResultOf(updates[:i], nil, nil), fmt.Errorf("update record failure, Error: %w", err)
+		}
+	}
+	for i, ip := range creates {
+		if err := d.call(ctx, "CreateRecord", d.recordParams(zone, sub, recordType, ip), &recordResp{}); err != nil {
+			return ddns.ResultOf(updates, creates[:i], nil), fmt.Errorf("create record failure, Error: %w", err)
+		}
+	}
+	for i, r := range deletes {
+		id, _ := strconv.ParseUint(r.ID, 10, 64)
+		if err := d.call(ctx, "DeleteRecord", map[string]any{"Domain": zone, "RecordId": id}, &baseResp{}); err != nil {
+			return ddns.ResultOf(updates, creates, deletes[:i]), fmt.Errorf("delete record failure, Error: %w", err)
+		}
+	}
+
+	return ddns.ResultOf(updates, creates, deletes), nil
+}
+
+func (d *DNSPod) recordParams(zone string, sub string, recordType string, ipAddr string) map[string]any {
+	params := map[string]any{
+		"Domain":     zone,
+		"SubDomain":  sub,
+		"RecordType": recordType,
+		"RecordLine": d.line,
+		"Value":      ipAddr,
+	}
+	if d.ttl > 0 {
+		params["TTL"] = d.ttl
+	}
+	return params
+}
+
+func (d *DNSPod) GetDomainRecords(recordType string, domain string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	_, _, records, err := d.getRecords(ctx, recordType, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[string]bool)
+	for i := range records {
+		domains[records[i].Value] = true
+	}
+	return domains, nil
+}
+
+// getRecords returns the zone, the sub domain and the records of domain on the configured line
+func (d *DNSPod) getRecords(ctx context.Context, recordType string, domain string) (string, string, []record, error) {
+	zone, err := d.getZone(ctx, domain)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	sub := "@"
+	if domain != zone {
+		sub = strings.TrimSuffix(domain, "."+zone)
+	}
+
+	rtn := &recordListResp{}
+	if err := d.call(ctx, "DescribeRecordList", map[string]any{
+		"Domain":     zone,
+		"Subdomain":  sub,
+		"RecordType": recordType,
+		"RecordLine": d.line,
+	}, rtn); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == "ResourceNotFound.NoDataOfRecord" {
+			return zone, sub, nil, nil
+		}
+		return "", "", nil, err
+	}
+
+	return zone, sub, rtn.Response.RecordList, nil
+}
+
+// getZone finds the longest zone in the account that domain belongs to
+func (d *DNSPod) getZone(ctx context.Context, domain string) (string, error) {
+	rtn := &domainListResp{}
+	if err := d.call(ctx, "DescribeDomainList", map[string]any{"Limit": 3000}, rtn); err != nil {
+		return "", err
+	}
+
+	zones := make([]string, len(rtn.Response.DomainList))
+	for i := range rtn.Response.DomainList {
+		zones[i] = rtn.Response.DomainList[i].Name
+	}
+	zone := ddns.MatchZone(domain, zones)
+	if zone == "" {
+		return "", fmt.Errorf("%w: cannot find a valid zone for %s", ddns.ErrZoneNotFound, domain)
+	}
+	return zone, nil
+}
+
+// call signs and sends an API 3.0 request, then decodes the response into result
+func (d *DNSPod) call(ctx context.Context, action string, params map[string]any, result any) error {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	resp, err := d.client.R().SetContext(ctx).
+		SetHeaders(map[string]string{
+			"Content-Type":   "application/json; charset=utf-8",
+			"Authorization":  d.sign(payload, now),
+			"X-TC-Action":    action,
+			"X-TC-Version":   apiVersion,
+			"X-TC-Timestamp": strconv.FormatInt(now.Unix(), 10),
+		}).
+		SetBody(payload).
+		Post("/")
+	if err != nil {
+		return ddns.WrapNetError(err)
+	}
+	if resp.StatusCode() >= 500 {
+		return ddns.StatusError(resp.StatusCode(), resp.String())
+	}
+
+	base := &struct {
+		Response baseResp `json:"Response"`
+	}{}
+	if err := json.Unmarshal(resp.Body(), base); err != nil {
+		return fmt.Errorf("[DNSPod] %s", resp.String())
+	}
+	if base.Response.Error != nil {
+		return base.Response.Error.wrap()
+	}
+
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// sign builds the TC3-HMAC-SHA256 authorization header
+func (d *DNSPod) sign(payload []byte, t time.Time) string {
+	date := t.UTC().Format("2006-01-02")
+	signedHeaders := "content-type;host"
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		"/",
+		"",
+		"content-type:application/json; charset=utf-8\nhost:" + d.host + "\n",
+		signedHeaders,
+		sha256Hex(payload),
+	}, "\n")
+
+	scope := date + "/" + d.service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		algorithm,
+		strconv.FormatInt(t.Unix(), 10),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+d.secretKey), date)
+	secretService := hmacSHA256(secretDate, d.service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, d.secretID, scope, signedHeaders, signature)
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("[DNSPod] %s: %s", e.Code, e.Message)
+}
+
+// wrap maps the api error code to the ddns errors, see https://cloud.tencent.com/document/api/1427/56192
+func (e *apiError) wrap() error {
+	switch {
+	case strings.HasPrefix(e.Code, "AuthFailure"), strings.HasPrefix(e.Code, "UnauthorizedOperation"):
+		return fmt.Errorf("%w: %w", ddns.ErrAuth, e)
+	case strings.HasPrefix(e.Code, "RequestLimitExceeded"):
+		return fmt.Errorf("%w: %w", ddns.ErrRateLimited, e)
+	case strings.HasPrefix(e.Code, "InternalError"), e.Code == "ResourceUnavailable", e.Code == "FailedOperation.TemporaryError":
+		return fmt.Errorf("%w: %w", ddns.ErrTransient, e)
+	case e.Code == "ResourceNotFound.NoDataOfDomain", e.Code == "InvalidParameter.DomainNotExist":
+		return fmt.Errorf("%w: %w", ddns.ErrZoneNotFound, e)
+	default:
+		return e
+	}
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
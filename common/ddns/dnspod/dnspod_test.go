@@ -0,0 +1,167 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+type fakeDNSPod struct {
+	t       *testing.T
+	d       *DNSPod
+	records []record
+	nextID  uint64
+}
+
+func (f *fakeDNSPod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	ts, _ := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+	if r.Header.Get("Authorization") != f.d.sign(body, time.Unix(ts, 0)) {
+		writeResp(w, map[string]any{"Error": apiError{Code: "AuthFailure.SignatureFailure", Message: "bad signature"}})
+		return
+	}
+
+	params := map[string]any{}
+	_ = json.Unmarshal(body, &params)
+
+	switch r.Header.Get("X-TC-Action") {
+	case "DescribeDomainList":
+		writeResp(w, map[string]any{"DomainList": []map[string]any{
+			{"DomainId": 1, "Name": "example.com"},
+			{"DomainId": 2, "Name": "example.com.cn"},
+		}})
+	case "DescribeRecordList":
+		var list []record
+		for _, rec := range f.records {
+			if rec.Name == params["Subdomain"] && rec.Type == params["RecordType"] && rec.Line == params["RecordLine"] {
+				list = append(list, rec)
+			}
+		}
+		if len(list) == 0 {
+			writeResp(w, map[string]any{"Error": apiError{Code: "ResourceNotFound.NoDataOfRecord", Message: "no record"}})
+			return
+		}
+		writeResp(w, map[string]any{"RecordList": list})
+	case "CreateRecord":
+		f.nextID++
+		f.records = append(f.records, record{
+			RecordId: f.nextID,
+			Name:     params["SubDomain"].(string),
+			Type:     params["RecordType"].(string),
+			Value:    params["Value"].(string),
+			Line:     params["RecordLine"].(string),
+		})
+		writeResp(w, map[string]any{"RecordId": f.nextID})
+	case "ModifyRecord":
+		for i := range f.records {
+			if float64(f.records[i].RecordId) == params["RecordId"] {
+				f.records[i].Value = params["Value"].(string)
+			}
+		}
+		writeResp(w, map[string]any{"RecordId": params["RecordId"]})
+	default:
+		f.t.Errorf("unexpected action %s", r.Header.Get("X-TC-Action"))
+	}
+}
+
+func writeResp(w http.ResponseWriter, resp map[string]any) {
+	resp["RequestId"] = "test"
+	_ = json.NewEncoder(w).Encode(map[string]any{"Response": resp})
+}
+
+func newTestDNSPod(t *testing.T, line string) (*DNSPod, *fakeDNSPod) {
+	f := &fakeDNSPod{t: t}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	d, err := New(map[string]string{
+		strings.ToLower("DNSPOD_SECRET_ID"):  "id",
+		strings.ToLower("DNSPOD_SECRET_KEY"): "key",
+		strings.ToLower("DNSPOD_LINE"):       line,
+		strings.ToLower("DNSPOD_ENDPOINT"):   srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.d = d
+	return d, f
+}
+
+func TestDNSPod_AddUpdateDomainRecords(t *testing.T) {
+	d, f := newTestDNSPod(t, "")
+
+	if _, err := d.AddUpdateDomainRecords("tcp4", "hk1.example.com.cn", "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.records) != 1 || f.records[0].Name != "hk1" || f.records[0].Line != defaultLine {
+		t.Fatalf("unexpected records: %+v", f.records)
+	}
+
+	if res, err := d.AddUpdateDomainRecords("tcp4", "hk1.example.com.cn", "1.2.3.4"); err != nil || res != ddns.Unchanged {
+		t.Errorf("expected unchanged: %v %v", res, err)
+	}
+
+	if _, err := d.AddUpdateDomainRecords("tcp4", "hk1.example.com.cn", "5.6.7.8"); err != nil {
+		t.Fatal(err)
+	}
+	ips, err := d.GetDomainRecords("A", "hk1.example.com.cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["5.6.7.8"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+}
+
+func TestDNSPod_Line(t *testing.T) {
+	d, f := newTestDNSPod(t, "境外")
+	f.records = []record{{RecordId: 100, Name: "@", Type: "AAAA", Value: "::1", Line: defaultLine}}
+
+	ips, err := d.GetDomainRecords("AAAA", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 0 {
+		t.Errorf("records on other lines should be ignored: %v", ips)
+	}
+
+	if _, err := d.AddUpdateDomainRecords("tcp6", "example.com", "::2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.records) != 2 || f.records[0].Value != "::1" || f.records[1].Line != "境外" {
+		t.Errorf("unexpected records: %+v", f.records)
+	}
+}
+
+func TestDNSPod_ZoneNotFound(t *testing.T) {
+	d, _ := newTestDNSPod(t, "")
+	if _, err := d.GetDomainRecords("A", "node.notexample.com"); !errors.Is(err, ddns.ErrZoneNotFound) {
+		t.Errorf("expected zone error, got %v", err)
+	}
+}
+
+// TestDNSPod_sign checks the signature against the example of the Tencent Cloud API 3.0 signature v3 document
+func TestDNSPod_sign(t *testing.T) {
+	d := &DNSPod{
+		secretID:  "AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE",
+		secretKey: "Gu5t9xGARNpq86cd98joQYCN3EXAMPLE",
+		host:      "cvm.tencentcloudapi.com",
+		service:   "cvm",
+	}
+	payload := `{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`
+
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE/2019-02-25/cvm/tc3_request, " +
+		"SignedHeaders=content-type;host, Signature=72e494ea809ad7a8c8f7a4507b9bddcbaa8e581f516e8da2f66e2c5a96525168"
+	if got := d.sign([]byte(payload), time.Unix(1551113065, 0)); got != want {
+		t.Errorf("unexpected authorization: %s", got)
+	}
+}
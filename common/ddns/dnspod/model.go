@@ -0,0 +1,44 @@
+package dnspod
+
+type apiError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type baseResp struct {
+	Error     *apiError `json:"Error"`
+	RequestId string    `json:"RequestId"`
+}
+
+type domainListResp struct {
+	Response struct {
+		baseResp
+		DomainList []struct {
+			DomainId uint64 `json:"DomainId"`
+			Name     string `json:"Name"`
+		} `json:"DomainList"`
+	} `json:"Response"`
+}
+
+type record struct {
+	RecordId uint64 `json:"RecordId"`
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	Line     string `json:"Line"`
+	TTL      uint64 `json:"TTL"`
+}
+
+type recordListResp struct {
+	Response struct {
+		baseResp
+		RecordList []record `json:"RecordList"`
+	} `json:"Response"`
+}
+
+type recordResp struct {
+	Response struct {
+		baseResp
+		RecordId uint64 `json:"RecordId"`
+	} `json:"Response"`
+}
@@ -0,0 +1,129 @@
+package dyndns2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+// Errors returned by the update endpoint, see https://help.dyn.com/remote-access-api/return-codes/
+var (
+	ErrBadAuth  = fmt.Errorf("%w: dyndns2 badauth, the username and password pair do not match", ddns.ErrAuth)
+	ErrNoHost   = fmt.Errorf("%w: dyndns2 nohost, the hostname does not exist in the account", ddns.ErrZoneNotFound)
+	ErrNotFQDN  = errors.New("dyndns2: notfqdn, the hostname is not a fully-qualified domain name")
+	ErrAbuse    = errors.New("dyndns2: abuse, the hostname is blocked for update abuse")
+	ErrBadAgent = errors.New("dyndns2: badagent, the user agent was not sent or is blocked")
+	Err911      = fmt.Errorf("%w: dyndns2 911, the server has a problem, retry later", ddns.ErrTransient)
+	ErrDNS      = fmt.Errorf("%w: dyndns2 dnserr, the server has a dns problem, retry later", ddns.ErrTransient)
+)
+
+// DynDNS2 Implementation for any dyndns2 protocol compatible endpoint
+type DynDNS2 struct {
+	nameserver string
+	client     *resty.Client
+}
+
+func New(c map[string]string) (*DynDNS2, error) {
+	baseURL := c[strings.ToLower("DYNDNS2_URL")]
+	if baseURL == "" {
+		return nil, errors.New("dyndns2 url is empty")
+	}
+
+	d := &DynDNS2{nameserver: c[strings.ToLower("DYNDNS2_NAMESERVER")]}
+	d.client = resty.New().SetBaseURL(baseURL).
+		SetBasicAuth(c[strings.ToLower("DYNDNS2_USERNAME")], c[strings.ToLower("DYNDNS2_PASSWORD")]).
+		SetHeader("User-Agent", "LightsailMon").
+		SetTimeout(time.Second * 10)
+
+	return d, nil
+}
+
+// AddUpdateDomainRecords create or update IPv4/IPv6 records
+func (d *DynDNS2) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	recordType, err := ddns.RecordType(network)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+	if ipAddr == "" {
+		return ddns.Unchanged, errors.New("IP address is nil")
+	}
+
+	return d.Reconcile(recordType, domain, []string{ipAddr})
+}
+
+// Reconcile sets a single address, the dyndns2 protocol cannot remove addresses or set several of them
+func (d *DynDNS2) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
+	current, err := d.GetDomainRecords(recordType, domain)
+	if err != nil {
+		current = map[string]bool{}
+	}
+
+	desired := make(map[string]bool)
+	for _, ip := range ips {
+		desired[ip] = true
+	}
+	if maps.Equal(current, desired) {
+		return ddns.Unchanged, nil
+	}
+	if len(desired) != 1 {
+		return ddns.Unchanged, ddns.ErrNotSupported
+	}
+
+	resp, err := d.client.R().SetQueryParams(map[string]string{
+		"hostname": domain,
+		"myip":     ips[0],
+	}).Get("/nic/update")
+	if err != nil {
+		return ddns.Unchanged, ddns.WrapNetError(err)
+	}
+
+	if err := parseResponse(resp.String()); err != nil {
+		return ddns.Unchanged, err
+	}
+	log.Infof("[%s] update record success, IP: %s", domain, ips[0])
+	if len(current) == 0 {
+		return ddns.Created, nil
+	}
+	return ddns.Updated, nil
+}
+
+// GetDomainRecords resolves the hostname against the configured nameserver
+func (d *DynDNS2) GetDomainRecords(recordType string, domain string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	return ddns.Resolve(ctx, d.nameserver, recordType, domain)
+}
+
+// parseResponse maps the return code of the update endpoint to an error, nochg is treated as success
+func parseResponse(body string) error {
+	code, _, _ := strings.Cut(strings.TrimSpace(body), " ")
+	switch code {
+	case "good", "nochg":
+		return nil
+	case "badauth":
+		return ErrBadAuth
+	case "nohost":
+		return ErrNoHost
+	case "notfqdn":
+		return ErrNotFQDN
+	case "abuse":
+		return ErrAbuse
+	case "badagent":
+		return ErrBadAgent
+	case "911":
+		return Err911
+	case "dnserr":
+		return ErrDNS
+	default:
+		return fmt.Errorf("dyndns2: unknown response: %s", body)
+	}
+}
@@ -0,0 +1,68 @@
+package dyndns2
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	tests := map[string]error{
+		"good 1.2.3.4":  nil,
+		"nochg 1.2.3.4": nil,
+		"badauth":       ErrBadAuth,
+		"nohost\n":      ErrNoHost,
+		"abuse":         ErrAbuse,
+		"911":           Err911,
+	}
+	for body, want := range tests {
+		if err := parseResponse(body); !errors.Is(err, want) {
+			t.Errorf("%q: got %v, want %v", body, err, want)
+		}
+	}
+	if err := parseResponse("<html>"); err == nil {
+		t.Error("expected unknown response error")
+	}
+}
+
+func TestDynDNS2_AddUpdateDomainRecords(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "username" || pass != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("badauth"))
+			return
+		}
+		if r.URL.Path != "/nic/update" || r.URL.Query().Get("hostname") != "node1.test.com" {
+			_, _ = w.Write([]byte("nohost"))
+			return
+		}
+		_, _ = w.Write([]byte("good " + r.URL.Query().Get("myip")))
+	}))
+	defer srv.Close()
+
+	newClient := func(password string) *DynDNS2 {
+		d, err := New(map[string]string{
+			strings.ToLower("DYNDNS2_URL"):        srv.URL,
+			strings.ToLower("DYNDNS2_USERNAME"):   "username",
+			strings.ToLower("DYNDNS2_PASSWORD"):   password,
+			strings.ToLower("DYNDNS2_NAMESERVER"): "127.0.0.1:1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	if _, err := newClient("password").AddUpdateDomainRecords("tcp4", "node1.test.com", "1.2.3.4"); err != nil {
+		t.Error(err)
+	}
+	if _, err := newClient("password").AddUpdateDomainRecords("tcp4", "node2.test.com", "1.2.3.4"); !errors.Is(err, ErrNoHost) {
+		t.Errorf("expected nohost, got %v", err)
+	}
+	if _, err := newClient("wrong").AddUpdateDomainRecords("tcp6", "node1.test.com", "2001:db8::1"); !errors.Is(err, ErrBadAuth) {
+		t.Errorf("expected badauth, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Resolve looks up the A/AAAA records of domain against nameserver, the system resolver is used if nameserver is empty
+func Resolve(ctx context.Context, nameserver string, recordType string, domain string) (map[string]bool, error) {
+	network := "ip4"
+	if recordType == "AAAA" {
+		network = "ip6"
+	}
+
+	ips, err := NewResolver(nameserver).LookupIP(ctx, network, domain)
+	if err != nil {
+		if dnsErr, ok := errors.AsType[*net.DNSError](err); ok && dnsErr.IsNotFound {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+
+	domains := make(map[string]bool)
+	for i := range ips {
+		domains[ips[i].String()] = true
+	}
+	return domains, nil
+}
+
+// NewResolver returns a resolver which sends every query to nameserver
+func NewResolver(nameserver string) *net.Resolver {
+	if nameserver == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(strings.Trim(nameserver, "[]"), "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, nameserver)
+		},
+	}
+}
+
+// Nameservers returns the configured nameserver, or the authoritative nameservers of domain found by walking up its labels
+func Nameservers(ctx context.Context, nameserver string, domain string) ([]string, error) {
+	if nameserver != "" {
+		return []string{nameserver}, nil
+	}
+
+	name := strings.TrimSuffix(domain, ".")
+	for strings.Contains(name, ".") {
+		if ns, err := net.DefaultResolver.LookupNS(ctx, name); err == nil && len(ns) > 0 {
+			servers := make([]string, len(ns))
+			for i := range ns {
+				servers[i] = strings.TrimSuffix(ns[i].Host, ".")
+			}
+			return servers, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+
+	return nil, fmt.Errorf("cannot find the authoritative nameservers of %s", domain)
+}
+
+// WaitPropagation polls every nameserver until all of them answer ip for domain, and returns the time it took
+func WaitPropagation(ctx context.Context, nameservers []string, recordType string, domain string, ip string, interval time.Duration) (time.Duration, error) {
+	start := time.Now()
+	pending := slices.Clone(nameservers)
+
+	for {
+		pending = slices.DeleteFunc(pending, func(ns string) bool {
+			ips, err := Resolve(ctx, ns, recordType, domain)
+			return err == nil && ips[ip]
+		})
+		if len(pending) == 0 {
+			return time.Since(start), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return time.Since(start), fmt.Errorf("%s is not propagated to %s: %w", ip, strings.Join(pending, ", "), ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
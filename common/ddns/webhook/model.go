@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"regexp"
+	"text/template"
+)
+
+// request describes one templated HTTP call and how to judge its response
+type request struct {
+	method  string
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+	matcher *matcher
+	ipRegex *regexp.Regexp
+	ipJSON  string
+}
+
+// matcher decides whether a response is successful
+type matcher struct {
+	status []int
+	regex  *regexp.Regexp
+	json   string
+	value  string
+}
+
+// templateData is the data exposed to the request templates
+type templateData struct {
+	Domain     string
+	IP         string
+	RecordType string
+}
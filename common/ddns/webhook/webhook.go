@@ -0,0 +1,347 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+// funcs are the helpers available in the request templates
+var funcs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+// Webhook Implementation, the update and query requests are fully described in config
+type Webhook struct {
+	update *request
+	query  *request
+	delete *request
+	client *resty.Client
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func New(c map[string]string) (*Webhook, error) {
+	w := &Webhook{
+		client: resty.New().SetTimeout(time.Second * 10),
+		cache:  make(map[string]string),
+	}
+
+	var err error
+	if w.update, err = newRequest(c, "WEBHOOK_UPDATE"); err != nil {
+		return nil, err
+	}
+	if w.update == nil {
+		return nil, errors.New("webhook update url is empty")
+	}
+	if w.query, err = newRequest(c, "WEBHOOK_QUERY"); err != nil {
+		return nil, err
+	}
+	if w.delete, err = newRequest(c, "WEBHOOK_DELETE"); err != nil {
+		return nil, err
+	}
+	if w.query != nil && w.query.ipRegex == nil && w.query.ipJSON == "" {
+		return nil, errors.New("webhook query needs an ip regex or ip json path")
+	}
+
+	return w, nil
+}
+
+// AddUpdateDomainRecords create or update IPv4/IPv6 records
+func (w *Webhook) AddUpdateDomainRecords(network string, domain string, ipAddr string) (ddns.Result, error) {
+	recordType, err := ddns.RecordType(network)
+	if err != nil {
+		return ddns.Unchanged, err
+	}
+	if ipAddr == "" {
+		return ddns.Unchanged, errors.New("IP address is nil")
+	}
+
+	return w.Reconcile(recordType, domain, []string{ipAddr})
+}
+
+// Reconcile sets a single address with the update request, and removes addresses with the delete request if it is configured
+func (w *Webhook) Reconcile(recordType string, domain string, ips []string) (ddns.Result, error) {
+	current, err := w.GetDomainRecords(recordType, domain)
+	if err != nil {
+		if w.query != nil {
+			return ddns.Unchanged, err
+		}
+		// nothing has been updated yet
+		current = map[string]bool{}
+	}
+
+	desired := make(map[string]bool)
+	for _, ip := range ips {
+		desired[ip] = true
+	}
+	if maps.Equal(current, desired) {
+		return ddns.Unchanged, nil
+	}
+
+	switch len(desired) {
+	case 0:
+		if w.delete == nil {
+			return ddns.Unchanged, ddns.ErrNotSupported
+		}
+		res := ddns.Unchanged
+		for ip := range current {
+			if _, err := w.do(w.delete, &templateData{Domain: domain, IP: ip, RecordType: recordType}); err != nil {
+				return res, fmt.Errorf("delete record failure, Error: %w", err)
+			}
+			res = ddns.Deleted
+		}
+
+		w.mu.Lock()
+		delete(w.cache, recordType+domain)
+		w.mu.Unlock()
+		return ddns.Deleted, nil
+	case 1:
+		if _, err := w.do(w.update, &templateData{Domain: domain, IP: ips[0], RecordType: recordType}); err != nil {
+			return ddns.Unchanged, fmt.Errorf("update record failure, Error: %w", err)
+		}
+
+		w.mu.Lock()
+		w.cache[recordType+domain] = ips[0]
+		w.mu.Unlock()
+		if len(current) == 0 {
+			return ddns.Created, nil
+		}
+		return ddns.Updated, nil
+	default:
+		return ddns.Unchanged, ddns.ErrNotSupported
+	}
+}
+
+// GetDomainRecords queries the records with the query request, or returns the last updated IP if it is not configured
+func (w *Webhook) GetDomainRecords(recordType string, domain string) (map[string]bool, error) {
+	domains := make(map[string]bool)
+
+	if w.query == nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if ip, ok := w.cache[recordType+domain]; ok {
+			domains[ip] = true
+			return domains, nil
+		}
+		return nil, errors.New("no record cache")
+	}
+
+	body, err := w.do(w.query, &templateData{Domain: domain, RecordType: recordType})
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	if w.query.ipJSON != "" {
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, err
+		}
+		for _, val := range lookup(v, strings.Split(w.query.ipJSON, ".")) {
+			values = append(values, fmt.Sprint(val))
+		}
+	} else {
+		for _, m := range w.query.ipRegex.FindAllSubmatch(body, -1) {
+			values = append(values, string(m[len(m)-1]))
+		}
+	}
+
+	for _, val := range values {
+		ip := net.ParseIP(val)
+		if ip == nil {
+			continue
+		}
+		if (recordType == "A") == (ip.To4() != nil) {
+			domains[ip.String()] = true
+		}
+	}
+	return domains, nil
+}
+
+// do renders and sends the request, and returns the body when the response matches
+func (w *Webhook) do(r *request, data *templateData) ([]byte, error) {
+	url, err := render(r.url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	req := w.client.R()
+	for k, h := range r.headers {
+		v, err := render(h, data)
+		if err != nil {
+			return nil, err
+		}
+		req.SetHeader(k, v)
+	}
+	if r.body != nil {
+		body, err := render(r.body, data)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBody(body)
+	}
+
+	resp, err := req.Execute(r.method, url)
+	if err != nil {
+		return nil, ddns.WrapNetError(err)
+	}
+	if err := r.matcher.match(resp.StatusCode(), resp.Body()); err != nil {
+		return nil, err
+	}
+
+	return resp.Body(), nil
+}
+
+func (m *matcher) match(status int, body []byte) error {
+	if len(m.status) > 0 {
+		if !slices.Contains(m.status, status) {
+			return ddns.StatusError(status, string(body))
+		}
+	} else if status < 200 || status > 299 {
+		return ddns.StatusError(status, string(body))
+	}
+
+	if m.regex != nil && !m.regex.Match(body) {
+		return fmt.Errorf("response not match %s: %s", m.regex, body)
+	}
+
+	if m.json != "" {
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fmt.Errorf("response is not json: %s", body)
+		}
+		ok := false
+		for _, val := range lookup(v, strings.Split(m.json, ".")) {
+			if m.value == "" && val != nil && val != false && val != "" || m.value != "" && fmt.Sprint(val) == m.value {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("response not match %s: %s", m.json, body)
+		}
+	}
+
+	return nil
+}
+
+// newRequest builds the request from the config keys with the given prefix, it returns nil if no url is set
+func newRequest(c map[string]string, prefix string) (*request, error) {
+	get := func(key string) string {
+		return c[strings.ToLower(prefix+"_"+key)]
+	}
+
+	if get("URL") == "" {
+		return nil, nil
+	}
+
+	r := &request{
+		method:  strings.ToUpper(get("METHOD")),
+		headers: make(map[string]*template.Template),
+		matcher: &matcher{json: get("SUCCESS_JSON")},
+		ipJSON:  get("IP_JSON"),
+	}
+	if r.method == "" {
+		r.method = "GET"
+	}
+
+	var err error
+	if r.url, err = template.New("url").Funcs(funcs).Parse(get("URL")); err != nil {
+		return nil, err
+	}
+	if body := get("BODY"); body != "" {
+		if r.body, err = template.New("body").Funcs(funcs).Parse(body); err != nil {
+			return nil, err
+		}
+	}
+
+	// headers are written one per line as "Key: Value"
+	for _, line := range strings.Split(get("HEADERS"), "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if r.headers[strings.TrimSpace(k)], err = template.New(k).Funcs(funcs).Parse(strings.TrimSpace(v)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range strings.Split(get("SUCCESS_STATUS"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_SUCCESS_STATUS: %s", prefix, s)
+		}
+		r.matcher.status = append(r.matcher.status, code)
+	}
+	if re := get("SUCCESS_REGEX"); re != "" {
+		if r.matcher.regex, err = regexp.Compile(re); err != nil {
+			return nil, err
+		}
+	}
+	if path, value, ok := strings.Cut(r.matcher.json, "="); ok {
+		r.matcher.json, r.matcher.value = path, value
+	}
+	if re := get("IP_REGEX"); re != "" {
+		if r.ipRegex, err = regexp.Compile(re); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func render(t *template.Template, data *templateData) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// lookup resolves a dot separated path in a decoded json value, "*" walks every array element
+func lookup(v any, path []string) []any {
+	if len(path) == 0 || len(path) == 1 && path[0] == "" {
+		return []any{v}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		if next, ok := val[path[0]]; ok {
+			return lookup(next, path[1:])
+		}
+	case []any:
+		if path[0] == "*" {
+			var rtn []any
+			for i := range val {
+				rtn = append(rtn, lookup(val[i], path[1:])...)
+			}
+			return rtn
+		}
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(val) {
+			return lookup(val[i], path[1:])
+		}
+	}
+
+	return nil
+}
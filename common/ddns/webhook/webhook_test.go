@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/ddns"
+)
+
+func TestWebhook_DuckDNS(t *testing.T) {
+	var ip string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("domains") != "hk1" || r.URL.Query().Get("token") != "secret" {
+			_, _ = w.Write([]byte("KO"))
+			return
+		}
+		ip = r.URL.Query().Get("ip")
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	w, err := New(map[string]string{
+		strings.ToLower("WEBHOOK_UPDATE_URL"):           srv.URL + `/update?domains={{trimSuffix ".duckdns.org" .Domain}}&token=secret&ip={{.IP}}`,
+		strings.ToLower("WEBHOOK_UPDATE_SUCCESS_REGEX"): "^OK$",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.AddUpdateDomainRecords("tcp4", "hk1.duckdns.org", "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if ip != "1.2.3.4" {
+		t.Errorf("unexpected ip: %s", ip)
+	}
+
+	// without a query request the last update is cached
+	if res, err := w.AddUpdateDomainRecords("tcp4", "hk1.duckdns.org", "1.2.3.4"); err != nil || res != ddns.Unchanged {
+		t.Errorf("expected unchanged: %v %v", res, err)
+	}
+
+	if _, err := w.AddUpdateDomainRecords("tcp4", "bad.duckdns.org", "1.2.3.4"); err == nil {
+		t.Error("expected regex mismatch error")
+	}
+}
+
+func TestWebhook_JSON(t *testing.T) {
+	records := map[string][]string{"A": {"9.9.9.9"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var rtn []map[string]string
+			for _, v := range records[r.URL.Query().Get("type")] {
+				rtn = append(rtn, map[string]string{"content": v})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": rtn})
+		case http.MethodPut:
+			body := map[string]string{}
+			b, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(b, &body)
+			records[body["type"]] = []string{body["content"]}
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "ok"})
+		}
+	}))
+	defer srv.Close()
+
+	w, err := New(map[string]string{
+		strings.ToLower("WEBHOOK_UPDATE_METHOD"):         "put",
+		strings.ToLower("WEBHOOK_UPDATE_URL"):            srv.URL + "/records/{{.Domain}}",
+		strings.ToLower("WEBHOOK_UPDATE_HEADERS"):        "Authorization: Bearer secret\nContent-Type: application/json",
+		strings.ToLower("WEBHOOK_UPDATE_BODY"):           `{"type":"{{.RecordType}}","content":"{{.IP}}"}`,
+		strings.ToLower("WEBHOOK_UPDATE_SUCCESS_STATUS"): "200, 202",
+		strings.ToLower("WEBHOOK_UPDATE_SUCCESS_JSON"):   "status=ok",
+		strings.ToLower("WEBHOOK_QUERY_URL"):             srv.URL + "/records/{{.Domain}}?type={{.RecordType}}",
+		strings.ToLower("WEBHOOK_QUERY_HEADERS"):         "Authorization: Bearer secret",
+		strings.ToLower("WEBHOOK_QUERY_IP_JSON"):         "result.*.content",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ips, err := w.GetDomainRecords("A", "hk1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["9.9.9.9"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+
+	if _, err := w.AddUpdateDomainRecords("tcp6", "hk1.example.com", "2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	ips, err = w.GetDomainRecords("AAAA", "hk1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips["2001:db8::1"] {
+		t.Errorf("unexpected ips: %v", ips)
+	}
+
+	if res, err := w.AddUpdateDomainRecords("tcp4", "hk1.example.com", "9.9.9.9"); err != nil || res != ddns.Unchanged {
+		t.Errorf("expected unchanged: %v %v", res, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var v any
+	_ = json.Unmarshal([]byte(`{"a":{"b":[{"c":1},{"c":2}]}}`), &v)
+
+	if got := lookup(v, strings.Split("a.b.*.c", ".")); len(got) != 2 {
+		t.Errorf("unexpected wildcard result: %v", got)
+	}
+	if got := lookup(v, strings.Split("a.b.1.c", ".")); len(got) != 1 || got[0] != float64(2) {
+		t.Errorf("unexpected index result: %v", got)
+	}
+	if got := lookup(v, strings.Split("a.x", ".")); got != nil {
+		t.Errorf("unexpected missing result: %v", got)
+	}
+}
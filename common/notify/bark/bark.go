@@ -0,0 +1,77 @@
+package bark
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const defaultServer = "https://api.day.app"
+
+// levels maps the severities to the bark interruption levels
+var levels = map[notify.Severity]string{
+	notify.Info:    "passive",
+	notify.Warning: "active",
+	notify.Error:   "timeSensitive",
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Bark, error) {
+	b := &Bark{
+		Server:    c[strings.ToLower("BARK_SERVER")],
+		DeviceKey: c[strings.ToLower("BARK_DEVICE_KEY")],
+		Group:     c[strings.ToLower("BARK_GROUP")],
+		Sound:     c[strings.ToLower("BARK_SOUND")],
+		Templates: templates,
+	}
+	if b.DeviceKey == "" {
+		return nil, errors.New("bark device key is empty")
+	}
+	if b.Server == "" {
+		b.Server = defaultServer
+	}
+
+	return b, nil
+}
+
+// Send pushes the event with the interruption level of its severity
+func (b *Bark) Send(e *notify.Event) error {
+	title, content, err := b.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+	return b.push(title, content, e.Severity)
+}
+
+func (b *Bark) push(title string, content string, severity notify.Severity) error {
+	rtn := &pushResp{}
+	resp, err := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R().
+		SetResult(rtn).
+		SetError(rtn).
+		ForceContentType("application/json").
+		SetBody(&pushReq{
+			DeviceKey: b.DeviceKey,
+			Title:     title,
+			Body:      content,
+			Level:     levels[severity],
+			Group:     b.Group,
+			Sound:     b.Sound,
+		}).
+		Post(strings.TrimSuffix(b.Server, "/") + "/push")
+	if err != nil {
+		return err
+	}
+
+	if rtn.Code != 200 {
+		if rtn.Message != "" {
+			return fmt.Errorf("[Bark] %d: %s", rtn.Code, rtn.Message)
+		}
+		return fmt.Errorf("[Bark] %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
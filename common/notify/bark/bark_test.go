@@ -0,0 +1,50 @@
+package bark
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeBark struct {
+	reqs []pushReq
+}
+
+func (f *fakeBark) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := pushReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	if r.URL.Path != "/push" || req.DeviceKey != "key" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":400,"message":"failed to get device token: device token is empty","timestamp":1}`))
+		return
+	}
+
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"code":200,"message":"success","timestamp":1}`))
+}
+
+func TestBark_Send(t *testing.T) {
+	f := &fakeBark{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	b, err := New(map[string]string{"bark_server": srv.URL, "bark_device_key": "key", "bark_group": "LightsailMon"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Send(notify.NewEvent(notify.EventRotationFailed)); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Level != "timeSensitive" || f.reqs[0].Group != "LightsailMon" {
+		t.Errorf("unexpected requests: %+v", f.reqs)
+	}
+
+	b.DeviceKey = "wrong"
+	if err := b.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[Bark] 400: failed to get device token: device token is empty" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package bark
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Bark struct {
+	Server    string
+	DeviceKey string
+	Group     string
+	Sound     string
+	Templates *notify.Templates
+}
+
+type pushReq struct {
+	DeviceKey string `json:"device_key"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Level     string `json:"level"`
+	Group     string `json:"group,omitempty"`
+	Sound     string `json:"sound,omitempty"`
+}
+
+type pushResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
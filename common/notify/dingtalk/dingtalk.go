@@ -0,0 +1,93 @@
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// severityColors are the title colors of the severities
+var severityColors = map[notify.Severity]string{
+	notify.Info:    "#2eb67d",
+	notify.Warning: "#ecb22e",
+	notify.Error:   "#e01e5a",
+}
+
+func New(c map[string]string, templates *notify.Templates) (*DingTalk, error) {
+	d := &DingTalk{
+		WebhookURL: c[strings.ToLower("DINGTALK_WEBHOOK_URL")],
+		Secret:     c[strings.ToLower("DINGTALK_SECRET")],
+		Templates:  templates,
+	}
+	if d.WebhookURL == "" {
+		return nil, errors.New("dingtalk webhook url is empty")
+	}
+
+	return d, nil
+}
+
+// Send posts the event as a markdown message, the title is colored by severity
+func (d *DingTalk) Send(e *notify.Event) error {
+	title, content, err := d.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	// dingtalk markdown needs two spaces before a line break
+	lines := []string{
+		fmt.Sprintf("### <font color=\"%s\">%s</font>", severityColors[e.Severity], title),
+		strings.ReplaceAll(content, "\n", "  \n"),
+		"",
+	}
+	for _, f := range e.Fields() {
+		lines = append(lines, fmt.Sprintf("- %s: %s", f.Name, f.Value))
+	}
+
+	return d.post(title, strings.Join(lines, "  \n"))
+}
+
+func (d *DingTalk) post(title string, markdown string) error {
+	req := &markdownReq{MsgType: "markdown"}
+	req.Markdown.Title = title
+	req.Markdown.Text = markdown
+
+	r := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R()
+	if d.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		r.SetQueryParams(map[string]string{
+			"timestamp": timestamp,
+			"sign":      sign(timestamp, d.Secret),
+		})
+	}
+
+	rtn := &robotResp{}
+	resp, err := r.SetResult(rtn).ForceContentType("application/json").SetBody(req).Post(d.WebhookURL)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("[DingTalk] %d: %s", resp.StatusCode(), resp.String())
+	}
+	if rtn.ErrCode != 0 {
+		return fmt.Errorf("[DingTalk] %d: %s", rtn.ErrCode, rtn.ErrMsg)
+	}
+
+	return nil
+}
+
+// sign signs the millisecond timestamp with the secret, see https://open.dingtalk.com/document/robots/customize-robot-security-settings
+func sign(timestamp string, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeDingTalk struct {
+	secret string
+	reqs   []markdownReq
+}
+
+func (f *fakeDingTalk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	h := hmac.New(sha256.New, []byte(f.secret))
+	h.Write([]byte(q.Get("timestamp") + "\n" + f.secret))
+	if q.Get("access_token") != "token" || q.Get("sign") != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+		return
+	}
+
+	req := markdownReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+}
+
+func TestDingTalk_Send(t *testing.T) {
+	f := &fakeDingTalk{secret: "SECxxx"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	d, err := New(map[string]string{
+		"dingtalk_webhook_url": srv.URL + "/robot/send?access_token=token",
+		"dingtalk_secret":      "SECxxx",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventRotationSucceeded)
+	e.Node, e.OldIP, e.NewIP = "node1.test.com(tcp4)", "1.1.1.1", "2.2.2.2"
+	e.Details = []string{"node1.test.com: propagated in 5s"}
+	if err := d.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Markdown.Title != "node1.test.com(tcp4)" ||
+		!strings.Contains(f.reqs[0].Markdown.Text, "IP changed: 2.2.2.2  \nnode1.test.com: propagated in 5s") ||
+		!strings.Contains(f.reqs[0].Markdown.Text, "- Old IP: 1.1.1.1") {
+		t.Errorf("unexpected requests: %+v", f.reqs)
+	}
+
+	d.Secret = "wrong"
+	if err := d.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[DingTalk] 310000: sign not match" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
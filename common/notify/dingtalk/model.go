@@ -0,0 +1,24 @@
+package dingtalk
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type DingTalk struct {
+	WebhookURL string
+	Secret     string // the signing secret of the robot security settings
+	Templates  *notify.Templates
+}
+
+type markdownReq struct {
+	MsgType  string `json:"msgtype"`
+	Markdown struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	} `json:"markdown"`
+}
+
+type robotResp struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
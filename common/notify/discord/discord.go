@@ -0,0 +1,100 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// maxRetryAfter caps the wait of a rate limited request
+const maxRetryAfter = time.Minute
+
+// retryInterval is the time to wait before retrying a failed request
+var retryInterval = time.Second * 5
+
+// severityColors are the embed colors of the severities
+var severityColors = map[notify.Severity]int{
+	notify.Info:    0x2ecc71,
+	notify.Warning: 0xf1c40f,
+	notify.Error:   0xe74c3c,
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Discord, error) {
+	d := &Discord{
+		WebhookURL: c[strings.ToLower("DISCORD_WEBHOOK_URL")],
+		Username:   c[strings.ToLower("DISCORD_USERNAME")],
+		Templates:  templates,
+	}
+	if d.WebhookURL == "" {
+		return nil, errors.New("discord webhook url is empty")
+	}
+
+	return d, nil
+}
+
+// Send posts the event as an embed colored by severity
+func (d *Discord) Send(e *notify.Event) error {
+	title, content, err := d.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	em := embed{
+		Title:       title,
+		Description: content,
+		Color:       severityColors[e.Severity],
+		Timestamp:   e.Time.UTC().Format(time.RFC3339),
+	}
+	for _, f := range e.Fields() {
+		em.Fields = append(em.Fields, embedField{Name: f.Name, Value: f.Value, Inline: true})
+	}
+
+	return d.post(&webhookReq{Username: d.Username, Embeds: []embed{em}})
+}
+
+// post posts the message, the rate limited and server failures are retried 3 times
+func (d *Discord) post(req *webhookReq) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		rtn := &errorResp{}
+		var resp *resty.Response
+		resp, err = resty.New().SetTimeout(time.Second * 10).R().
+			SetBody(req).
+			SetError(rtn).
+			ForceContentType("application/json").
+			Post(d.WebhookURL)
+
+		wait := retryInterval
+		switch {
+		case err != nil:
+		case resp.IsSuccess():
+			return nil
+		case resp.StatusCode() == http.StatusTooManyRequests:
+			err = fmt.Errorf("[Discord] rate limited: %s", rtn.Message)
+			if after := notify.RetryAfter(resp.Header().Get("Retry-After"), maxRetryAfter); after > 0 {
+				wait = after
+			} else if rtn.RetryAfter > 0 {
+				wait = min(time.Duration(rtn.RetryAfter*float64(time.Second)), maxRetryAfter)
+			}
+		case resp.StatusCode() >= 500:
+			err = fmt.Errorf("[Discord] %d: %s", resp.StatusCode(), resp.String())
+		default:
+			if rtn.Message != "" {
+				return fmt.Errorf("[Discord] %d: %s", rtn.Code, rtn.Message)
+			}
+			return fmt.Errorf("[Discord] %d: %s", resp.StatusCode(), resp.String())
+		}
+
+		log.Warnf("%v, attempt retry..(%d/3)", err, i+1)
+		time.Sleep(wait)
+	}
+
+	return err
+}
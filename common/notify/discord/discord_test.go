@@ -0,0 +1,78 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeDiscord struct {
+	reqs    []webhookReq
+	limited int
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/webhooks/1/token" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Unknown Webhook","code":10015}`))
+		return
+	}
+	if f.limited > 0 {
+		f.limited--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.01,"global":false}`))
+		return
+	}
+
+	req := webhookReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestDiscord(t *testing.T, f *fakeDiscord, path string) *Discord {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	interval := retryInterval
+	retryInterval = 0
+	t.Cleanup(func() { retryInterval = interval })
+
+	d, err := New(map[string]string{"discord_webhook_url": srv.URL + path, "discord_username": "LightsailMon"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDiscord_Send(t *testing.T) {
+	f := &fakeDiscord{limited: 1}
+	d := newTestDiscord(t, f, "/api/webhooks/1/token")
+
+	e := notify.NewEvent(notify.EventRotationFailed)
+	e.Node, e.Network, e.OldIP, e.NewIP = "node1.test.com(tcp4)", "tcp4", "1.1.1.1", "2.2.2.2"
+	if err := d.Send(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.reqs) != 1 || len(f.reqs[0].Embeds) != 1 {
+		t.Fatalf("unexpected requests: %+v", f.reqs)
+	}
+	em := f.reqs[0].Embeds[0]
+	if f.reqs[0].Username != "LightsailMon" || em.Title != "node1.test.com(tcp4)" || em.Color != severityColors[notify.Error] {
+		t.Errorf("unexpected embed: %+v", em)
+	}
+	if len(em.Fields) != 4 || em.Fields[2].Name != "Old IP" || em.Fields[3].Value != "2.2.2.2" {
+		t.Errorf("unexpected fields: %+v", em.Fields)
+	}
+}
+
+func TestDiscord_Error(t *testing.T) {
+	d := newTestDiscord(t, &fakeDiscord{}, "/api/webhooks/1/wrong")
+	if err := d.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[Discord] 10015: Unknown Webhook" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
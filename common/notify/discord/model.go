@@ -0,0 +1,37 @@
+package discord
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Discord struct {
+	WebhookURL string
+	Username   string
+	Templates  *notify.Templates
+}
+
+type webhookReq struct {
+	Username string  `json:"username,omitempty"`
+	Content  string  `json:"content,omitempty"`
+	Embeds   []embed `json:"embeds,omitempty"`
+}
+
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Color       int          `json:"color"`
+	Fields      []embedField `json:"fields,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type errorResp struct {
+	Code       int     `json:"code"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+}
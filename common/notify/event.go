@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// EventType is the kind of an event
+type EventType string
+
+const (
+	EventNodeBlocked       EventType = "node_blocked"
+	EventRotationStarted   EventType = "rotation_started"
+	EventRotationSucceeded EventType = "rotation_succeeded"
+	EventRotationFailed    EventType = "rotation_failed"
+	EventDDNSUpdated       EventType = "ddns_updated"
+	EventDDNSFailed        EventType = "ddns_failed"
+	EventNetworkDown       EventType = "network_down"
+	EventNetworkUp         EventType = "network_up"
+	EventCircuitBreaker    EventType = "circuit_breaker"
+	EventServiceStarted    EventType = "service_started"
+	EventServiceStopped    EventType = "service_stopped"
+	EventReport            EventType = "report"
+)
+
+// EventTypes lists every event type
+var EventTypes = []EventType{
+	EventNodeBlocked, EventRotationStarted, EventRotationSucceeded, EventRotationFailed, EventDDNSUpdated,
+	EventDDNSFailed, EventNetworkDown, EventNetworkUp, EventCircuitBreaker, EventServiceStarted,
+	EventServiceStopped, EventReport,
+}
+
+// Severity returns the default severity of the event type
+func (t EventType) Severity() Severity {
+	switch t {
+	case EventNodeBlocked, EventDDNSFailed, EventServiceStopped:
+		return Warning
+	case EventRotationFailed, EventNetworkDown, EventCircuitBreaker:
+		return Error
+	default:
+		return Info
+	}
+}
+
+// Event is a notification of something that happened to a node or to the service, the fields which do not apply to
+// the event are left empty
+type Event struct {
+	Type     EventType `json:"type"`
+	Severity Severity  `json:"severity"`
+	Time     time.Time `json:"time"`
+
+	Node     string        `json:"node,omitempty"` // the node display name, e.g. node1.test.com(tcp4)
+	Instance string        `json:"instance,omitempty"`
+	Region   string        `json:"region,omitempty"`
+	Network  string        `json:"network,omitempty"`
+	IP       string        `json:"ip,omitempty"` // the current node IP
+	OldIP    string        `json:"old_ip,omitempty"`
+	NewIP    string        `json:"new_ip,omitempty"`
+	Latency  time.Duration `json:"latency_ns,omitempty"`
+	Duration time.Duration `json:"duration_ns,omitempty"` // the period the event covers, e.g. of a report
+	Attempts int           `json:"attempts,omitempty"`
+	Details  []string      `json:"details,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// NewEvent creates an event of the type with its default severity
+func NewEvent(t EventType) *Event {
+	return &Event{
+		Type:     t,
+		Severity: t.Severity(),
+		Time:     time.Now(),
+	}
+}
+
+// WithError sets the event error if err is not nil
+func (e *Event) WithError(err error) *Event {
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// Field is a named value of an event, for the providers that render fields
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the non-empty node fields of the event
+func (e *Event) Fields() []Field {
+	var fields []Field
+	add := func(name string, value string) {
+		if value != "" {
+			fields = append(fields, Field{Name: name, Value: value})
+		}
+	}
+
+	add("Node", e.Node)
+	add("Network", e.Network)
+	add("Region", e.Region)
+	add("Old IP", e.OldIP)
+	add("New IP", e.NewIP)
+	if e.OldIP == "" && e.NewIP == "" {
+		add("IP", e.IP)
+	}
+	if e.Latency > 0 {
+		add("Latency", fmt.Sprintf("%d ms", e.Latency.Milliseconds()))
+	}
+	if e.Attempts > 0 {
+		add("Attempts", strconv.Itoa(e.Attempts))
+	}
+	if e.Duration > 0 {
+		add("Duration", e.Duration.String())
+	}
+
+	return fields
+}
+
+// RetryAfter parses the Retry-After header in seconds, the wait is capped by max
+func RetryAfter(header string, max time.Duration) time.Duration {
+	sec, err := strconv.ParseFloat(header, 64)
+	if err != nil || sec <= 0 {
+		return 0
+	}
+	return min(time.Duration(sec*float64(time.Second)), max)
+}
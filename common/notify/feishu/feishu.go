@@ -0,0 +1,96 @@
+package feishu
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// headerTemplates are the card header colors of the severities
+var headerTemplates = map[notify.Severity]string{
+	notify.Info:    "green",
+	notify.Warning: "orange",
+	notify.Error:   "red",
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Feishu, error) {
+	f := &Feishu{
+		WebhookURL: c[strings.ToLower("FEISHU_WEBHOOK_URL")],
+		Secret:     c[strings.ToLower("FEISHU_SECRET")],
+		Templates:  templates,
+	}
+	if f.WebhookURL == "" {
+		return nil, errors.New("feishu webhook url is empty")
+	}
+
+	return f, nil
+}
+
+// Send posts the event as a markdown card, the header is colored by severity
+func (f *Feishu) Send(e *notify.Event) error {
+	title, content, err := f.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	elements := []cardElement{{Tag: "markdown", Content: content}}
+	if fields := e.Fields(); len(fields) > 0 {
+		lines := make([]string, len(fields))
+		for i, field := range fields {
+			lines[i] = fmt.Sprintf("**%s**: %s", field.Name, field.Value)
+		}
+		elements = append(elements, cardElement{Tag: "hr"}, cardElement{Tag: "markdown", Content: strings.Join(lines, "\n")})
+	}
+
+	return f.post(title, headerTemplates[e.Severity], elements)
+}
+
+func (f *Feishu) post(title string, color string, elements []cardElement) error {
+	req := &cardReq{
+		MsgType: "interactive",
+		Card: card{
+			Header:   cardHeader{Title: cardText{Tag: "plain_text", Content: title}, Template: color},
+			Elements: elements,
+		},
+	}
+	if f.Secret != "" {
+		req.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		req.Sign = sign(req.Timestamp, f.Secret)
+	}
+
+	rtn := &botResp{}
+	resp, err := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R().
+		SetResult(rtn).
+		SetError(rtn).
+		ForceContentType("application/json").
+		SetBody(req).
+		Post(f.WebhookURL)
+	if err != nil {
+		return err
+	}
+
+	if rtn.Code != 0 {
+		return fmt.Errorf("[Feishu] %d: %s", rtn.Code, rtn.Msg)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("[Feishu] %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
+
+// sign signs the second timestamp with the secret, the string to sign is the hmac key and the message is empty, see
+// https://open.feishu.cn/document/client-docs/bot-v3/add-custom-bot
+func sign(timestamp string, secret string) string {
+	h := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
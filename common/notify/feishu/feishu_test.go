@@ -0,0 +1,65 @@
+package feishu
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeFeishu struct {
+	secret string
+	reqs   []cardReq
+}
+
+func (f *fakeFeishu) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := cardReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	h := hmac.New(sha256.New, []byte(req.Timestamp+"\n"+f.secret))
+	if r.URL.Path != "/open-apis/bot/v2/hook/token" || req.Sign != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+		_, _ = w.Write([]byte(`{"code":19021,"data":{},"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+		return
+	}
+
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"code":0,"data":{},"msg":"success"}`))
+}
+
+func TestFeishu_Send(t *testing.T) {
+	f := &fakeFeishu{secret: "secret"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	fs, err := New(map[string]string{
+		"feishu_webhook_url": srv.URL + "/open-apis/bot/v2/hook/token",
+		"feishu_secret":      "secret",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventNodeBlocked)
+	e.Node, e.Network, e.IP = "node1.test.com(tcp4)", "tcp4", "1.1.1.1"
+	if err := fs.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 {
+		t.Fatalf("unexpected requests: %+v", f.reqs)
+	}
+	c := f.reqs[0].Card
+	if f.reqs[0].MsgType != "interactive" || c.Header.Template != "orange" || c.Header.Title.Content != "node1.test.com(tcp4)" ||
+		len(c.Elements) != 3 || c.Elements[2].Content != "**Node**: node1.test.com(tcp4)\n**Network**: tcp4\n**IP**: 1.1.1.1" {
+		t.Errorf("unexpected card: %+v", c)
+	}
+
+	fs.Secret = "wrong"
+	if err := fs.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil {
+		t.Error("expected sign error")
+	}
+}
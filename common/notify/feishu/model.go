@@ -0,0 +1,43 @@
+package feishu
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Feishu struct {
+	WebhookURL string // open.feishu.cn or open.larksuite.com custom bot webhook
+	Secret     string // the signature verification secret of the bot
+	Templates  *notify.Templates
+}
+
+type cardReq struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Sign      string `json:"sign,omitempty"`
+	MsgType   string `json:"msg_type"`
+	Card      card   `json:"card"`
+}
+
+type card struct {
+	Header   cardHeader    `json:"header"`
+	Elements []cardElement `json:"elements"`
+}
+
+type cardHeader struct {
+	Title    cardText `json:"title"`
+	Template string   `json:"template"`
+}
+
+type cardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type cardElement struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content,omitempty"`
+}
+
+type botResp struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
@@ -0,0 +1,63 @@
+package gotify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// priorities maps the severities to the gotify priorities, the android client makes a sound from 4 and pops up from 8
+var priorities = map[notify.Severity]int{
+	notify.Info:    2,
+	notify.Warning: 5,
+	notify.Error:   8,
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Gotify, error) {
+	g := &Gotify{
+		Server:    c[strings.ToLower("GOTIFY_SERVER")],
+		Token:     c[strings.ToLower("GOTIFY_TOKEN")],
+		Templates: templates,
+	}
+	if g.Server == "" || g.Token == "" {
+		return nil, errors.New("gotify server or token is empty")
+	}
+
+	return g, nil
+}
+
+// Send pushes the event with the priority of its severity
+func (g *Gotify) Send(e *notify.Event) error {
+	title, content, err := g.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+	return g.push(title, content, e.Severity)
+}
+
+func (g *Gotify) push(title string, content string, severity notify.Severity) error {
+	rtn := &errorResp{}
+	resp, err := resty.New().SetTimeout(time.Second*10).SetRetryCount(3).R().
+		SetHeader("X-Gotify-Key", g.Token).
+		SetError(rtn).
+		ForceContentType("application/json").
+		SetBody(&messageReq{Title: title, Message: content, Priority: priorities[severity]}).
+		Post(strings.TrimSuffix(g.Server, "/") + "/message")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		if rtn.Error != "" {
+			return fmt.Errorf("[Gotify] %d %s: %s", rtn.ErrorCode, rtn.Error, rtn.ErrorDescription)
+		}
+		return fmt.Errorf("[Gotify] %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package gotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeGotify struct {
+	reqs []messageReq
+}
+
+func (f *fakeGotify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"Unauthorized","errorCode":401,"errorDescription":"you need to provide a valid access token"}`))
+		return
+	}
+
+	req := messageReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"id":1}`))
+}
+
+func TestGotify_Send(t *testing.T) {
+	f := &fakeGotify{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	g, err := New(map[string]string{"gotify_server": srv.URL + "/", "gotify_token": "token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventNodeBlocked)
+	e.Node, e.IP = "node1.test.com(tcp4)", "1.1.1.1"
+	if err := g.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Priority != 5 || f.reqs[0].Message != "Connection blocked: 1.1.1.1" {
+		t.Errorf("unexpected requests: %+v", f.reqs)
+	}
+
+	g.Token = "wrong"
+	if err := g.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil {
+		t.Error("expected token error")
+	}
+}
@@ -0,0 +1,23 @@
+package gotify
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Gotify struct {
+	Server    string
+	Token     string // the application token
+	Templates *notify.Templates
+}
+
+type messageReq struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+type errorResp struct {
+	Error            string `json:"error"`
+	ErrorCode        int    `json:"errorCode"`
+	ErrorDescription string `json:"errorDescription"`
+}
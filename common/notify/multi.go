@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Severity of an event, a channel only receives events at or above its severity
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+const (
+	// defaultTimeout is the max time to wait for a channel to send an event
+	defaultTimeout = time.Second * 30
+	// defaultQueueSize is the number of events a channel buffers while it is sending
+	defaultQueueSize = 100
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses the severity name, an empty name means info
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return Info, nil
+	case "warn", "warning":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	default:
+		return Info, fmt.Errorf("unknown severity: %s", s)
+	}
+}
+
+// Channel is a notifier with the filter of the messages it receives
+type Channel struct {
+	Name     string
+	Notifier Notify
+	Events   []EventType
+	Severity Severity
+}
+
+// Accept reports whether the channel receives the event, an empty event list accepts every event
+func (c *Channel) Accept(e *Event) bool {
+	if e.Severity < c.Severity {
+		return false
+	}
+	return len(c.Events) == 0 || slices.Contains(c.Events, e.Type)
+}
+
+// Multi sends an event to every accepting channel in the background, each channel has its own queue and worker so a
+// slow channel never holds up the caller or the other channels
+type Multi struct {
+	Channels  []*Channel
+	Timeout   time.Duration // the max time to wait for a channel to send an event, default: 30s
+	QueueSize int           // the events a channel buffers while it is sending, default: 100
+
+	once   sync.Once
+	mu     sync.RWMutex
+	queues []chan *Event
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// start starts a worker for every channel
+func (m *Multi) start() {
+	size := m.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	m.queues = make([]chan *Event, len(m.Channels))
+	for i, c := range m.Channels {
+		q := make(chan *Event, size)
+		m.queues[i] = q
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for e := range q {
+				m.deliver(c, e)
+			}
+		}()
+	}
+}
+
+// deliver sends the event to the channel and logs the failure. A channel which does not finish in time is left
+// running in the background, and the worker moves on to the next event.
+func (m *Multi) deliver(c *Channel, e *Event) {
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Notifier.Send(e)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Errorf("[%s] push %s event: %v", c.Name, e.Type, err)
+		}
+	case <-time.After(timeout):
+		log.Errorf("[%s] push %s event: timeout after %s", c.Name, e.Type, timeout)
+	}
+}
+
+// Send queues the event to every accepting channel and returns at once, the channel failures are logged. The event
+// is dropped by a channel whose queue is full, which is reported as an error.
+func (m *Multi) Send(e *Event) error {
+	m.once.Do(m.start)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return errors.New("notifier is closed")
+	}
+
+	var errs []error
+	for i, c := range m.Channels {
+		if !c.Accept(e) {
+			continue
+		}
+
+		select {
+		case m.queues[i] <- e:
+		default:
+			errs = append(errs, fmt.Errorf("%s: queue is full, drop %s event", c.Name, e.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close sends the queued events, and closes the channels which hold resources, e.g. flushes the digests
+func (m *Multi) Close() error {
+	m.once.Do(m.start)
+
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		for _, q := range m.queues {
+			close(q)
+		}
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+
+	var errs []error
+	for _, c := range m.Channels {
+		if err := closeNotify(c.Notifier); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
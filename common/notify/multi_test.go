@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeNotify struct {
+	delay time.Duration
+	block chan struct{} // the send waits until it is closed
+	err   error
+	calls atomic.Int32
+	sent  atomic.Int32
+}
+
+func (f *fakeNotify) Send(e *Event) error {
+	f.calls.Add(1)
+	time.Sleep(f.delay)
+	if f.block != nil {
+		<-f.block
+	}
+	f.sent.Add(1)
+	return f.err
+}
+
+func TestMulti_Send(t *testing.T) {
+	all, errorOnly, rotation := &fakeNotify{}, &fakeNotify{}, &fakeNotify{}
+	failed := &fakeNotify{err: errors.New("bad token")}
+	m := &Multi{Channels: []*Channel{
+		{Name: "all", Notifier: all},
+		{Name: "error", Notifier: errorOnly, Severity: Error},
+		{Name: "rotation", Notifier: rotation, Events: []EventType{EventRotationFailed}},
+		{Name: "failed", Notifier: failed},
+	}}
+
+	// the channel errors are logged instead of returned
+	if err := m.Send(NewEvent(EventRotationSucceeded)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := m.Send(NewEvent(EventRotationFailed)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if all.sent.Load() != 2 || errorOnly.sent.Load() != 1 || rotation.sent.Load() != 1 || failed.sent.Load() != 2 {
+		t.Errorf("unexpected sent: %d %d %d %d", all.sent.Load(), errorOnly.sent.Load(), rotation.sent.Load(), failed.sent.Load())
+	}
+
+	if err := m.Send(NewEvent(EventServiceStopped)); err == nil {
+		t.Error("expected closed error")
+	}
+}
+
+func TestMulti_Blocked(t *testing.T) {
+	fast, hung := &fakeNotify{}, &fakeNotify{block: make(chan struct{})}
+	m := &Multi{
+		Channels:  []*Channel{{Name: "fast", Notifier: fast}, {Name: "hung", Notifier: hung}},
+		QueueSize: 1,
+	}
+
+	// a hung channel never holds up the caller or the other channels
+	for i := range 3 {
+		start := time.Now()
+		err := m.Send(NewEvent(EventRotationStarted))
+		if elapsed := time.Since(start); elapsed > time.Millisecond*100 {
+			t.Errorf("a hung channel blocks the send: %s", elapsed)
+		}
+
+		// the hung channel holds one event in its worker and one in its queue, and drops the rest
+		if (i == 2) != (err != nil) {
+			t.Errorf("send %d: unexpected error: %v", i, err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for (fast.sent.Load() < int32(i+1) || hung.calls.Load() == 0) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 10)
+		}
+		if fast.sent.Load() != int32(i+1) {
+			t.Fatalf("the fast channel is held up: %d", fast.sent.Load())
+		}
+	}
+
+	close(hung.block)
+	_ = m.Close()
+}
+
+func TestMulti_Timeout(t *testing.T) {
+	fast, slow := &fakeNotify{}, &fakeNotify{delay: time.Second}
+	m := &Multi{
+		Channels: []*Channel{{Name: "fast", Notifier: fast}, {Name: "slow", Notifier: slow}},
+		Timeout:  time.Millisecond * 100,
+	}
+
+	_ = m.Send(NewEvent(EventServiceStarted))
+
+	// the slow channel is given up after the timeout
+	start := time.Now()
+	_ = m.Close()
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("a slow channel blocks the close: %s", elapsed)
+	}
+	if fast.sent.Load() != 1 {
+		t.Error("fast channel is not sent")
+	}
+}
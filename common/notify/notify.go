@@ -1,5 +1,6 @@
 package notify
 
+// Notify sends events, each provider renders them to its own message format
 type Notify interface {
-	Webhook(title string, content string) error
+	Send(e *Event) error
 }
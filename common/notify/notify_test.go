@@ -1,27 +1,32 @@
-package notify
+package notify_test
 
 import (
 	"testing"
 
+	"github.com/Septrum101/lightsailMon/common/notify"
 	"github.com/Septrum101/lightsailMon/common/notify/pushplus"
 	"github.com/Septrum101/lightsailMon/common/notify/telegram"
 )
 
-func TestTelegram_Webhook(t *testing.T) {
+func TestTelegram_Send(t *testing.T) {
 	tg := telegram.Telegram{
-		ChatID: 123,
+		ChatID: "123",
 		Token:  "YOUR_TOKEN",
 	}
-	err := tg.Webhook("node1.test.com", "This is test message")
+	e := notify.NewEvent(notify.EventServiceStarted)
+	e.Node = "node1.test.com"
+	err := tg.Send(e)
 	if err != nil {
 		t.Error(err)
 		return
 	}
 }
 
-func TestPushPlus_Webhook(t *testing.T) {
+func TestPushPlus_Send(t *testing.T) {
 	pp := pushplus.PushPlus{Token: "YOUR_TOKEN"}
-	err := pp.Webhook("node1.test.com", "This is test message")
+	e := notify.NewEvent(notify.EventServiceStarted)
+	e.Node = "node1.test.com"
+	err := pp.Send(e)
 	if err != nil {
 		t.Error(err)
 		return
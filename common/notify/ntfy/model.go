@@ -0,0 +1,26 @@
+package ntfy
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Ntfy struct {
+	Server    string
+	Topic     string
+	Token     string // access token, or the password with Username
+	Username  string
+	Templates *notify.Templates
+}
+
+type publishReq struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type errorResp struct {
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+}
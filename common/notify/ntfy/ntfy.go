@@ -0,0 +1,87 @@
+package ntfy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const defaultServer = "https://ntfy.sh"
+
+// priorities maps the severities to the ntfy priorities (1 min - 5 max)
+var priorities = map[notify.Severity]int{
+	notify.Info:    3,
+	notify.Warning: 4,
+	notify.Error:   5,
+}
+
+// tags are the emoji tags of the severities
+var tags = map[notify.Severity]string{
+	notify.Info:    "white_check_mark",
+	notify.Warning: "warning",
+	notify.Error:   "rotating_light",
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Ntfy, error) {
+	n := &Ntfy{
+		Server:    c[strings.ToLower("NTFY_SERVER")],
+		Topic:     c[strings.ToLower("NTFY_TOPIC")],
+		Token:     c[strings.ToLower("NTFY_TOKEN")],
+		Username:  c[strings.ToLower("NTFY_USERNAME")],
+		Templates: templates,
+	}
+	if n.Topic == "" {
+		return nil, errors.New("ntfy topic is empty")
+	}
+	if n.Server == "" {
+		n.Server = defaultServer
+	}
+
+	return n, nil
+}
+
+// Send publishes the event with the priority of its severity
+func (n *Ntfy) Send(e *notify.Event) error {
+	title, content, err := n.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+	return n.publish(title, content, e.Severity)
+}
+
+// publish posts the message as json to the server root, which carries the topic in the body
+func (n *Ntfy) publish(title string, content string, severity notify.Severity) error {
+	req := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R()
+	switch {
+	case n.Username != "":
+		req.SetBasicAuth(n.Username, n.Token)
+	case n.Token != "":
+		req.SetAuthToken(n.Token)
+	}
+
+	rtn := &errorResp{}
+	resp, err := req.SetError(rtn).ForceContentType("application/json").SetBody(&publishReq{
+		Topic:    n.Topic,
+		Title:    title,
+		Message:  content,
+		Priority: priorities[severity],
+		Tags:     []string{tags[severity]},
+	}).Post(strings.TrimSuffix(n.Server, "/"))
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		if rtn.Error != "" {
+			return fmt.Errorf("[ntfy] %d: %s", rtn.Code, rtn.Error)
+		}
+		return fmt.Errorf("[ntfy] %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
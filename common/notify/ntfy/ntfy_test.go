@@ -0,0 +1,52 @@
+package ntfy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeNtfy struct {
+	reqs []publishReq
+}
+
+func (f *fakeNtfy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer tk_token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":40101,"http":401,"error":"unauthorized"}`))
+		return
+	}
+
+	req := publishReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"id":"1","event":"message"}`))
+}
+
+func TestNtfy_Send(t *testing.T) {
+	f := &fakeNtfy{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	n, err := New(map[string]string{"ntfy_server": srv.URL, "ntfy_topic": "lightsail", "ntfy_token": "tk_token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventRotationFailed)
+	e.Node, e.Attempts = "node1.test.com(tcp4)", 3
+	if err := n.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Topic != "lightsail" || f.reqs[0].Priority != 5 || f.reqs[0].Title != "node1.test.com(tcp4)" {
+		t.Errorf("unexpected requests: %+v", f.reqs)
+	}
+
+	n.Token = "wrong"
+	if err := n.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[ntfy] 40101: unauthorized" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventDigest is the summary of the events batched by a Digest
+const EventDigest EventType = "digest"
+
+// Dedupe drops an event which is identical to one sent within the window, the details tell apart the events of
+// different domains on one node
+type Dedupe struct {
+	Next   Notify
+	Window time.Duration
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+func NewDedupe(next Notify, window time.Duration) *Dedupe {
+	return &Dedupe{Next: next, Window: window, sent: make(map[string]time.Time)}
+}
+
+func (d *Dedupe) Send(e *Event) error {
+	key := strings.Join([]string{
+		string(e.Type), e.Node, e.IP, e.OldIP, e.NewIP, e.Error, strings.Join(e.Details, "\n"),
+	}, "|")
+	now := time.Now()
+
+	d.mu.Lock()
+	for k, t := range d.sent {
+		if now.Sub(t) >= d.Window {
+			delete(d.sent, k)
+		}
+	}
+	if _, ok := d.sent[key]; ok {
+		d.mu.Unlock()
+		return nil
+	}
+	d.sent[key] = now
+	d.mu.Unlock()
+
+	return d.Next.Send(e)
+}
+
+func (d *Dedupe) Close() error {
+	return closeNotify(d.Next)
+}
+
+// Throttle sends at most Limit events per Period, the dropped events are counted in the next sent one
+type Throttle struct {
+	Next   Notify
+	Limit  int
+	Period time.Duration
+
+	mu         sync.Mutex
+	sent       []time.Time
+	suppressed int
+}
+
+func NewThrottle(next Notify, limit int, period time.Duration) *Throttle {
+	return &Throttle{Next: next, Limit: limit, Period: period}
+}
+
+func (t *Throttle) Send(e *Event) error {
+	now := time.Now()
+
+	t.mu.Lock()
+	t.sent = slices.DeleteFunc(t.sent, func(s time.Time) bool { return now.Sub(s) >= t.Period })
+	if len(t.sent) >= t.Limit {
+		t.suppressed++
+		t.mu.Unlock()
+		return nil
+	}
+	t.sent = append(t.sent, now)
+	suppressed := t.suppressed
+	t.suppressed = 0
+	t.mu.Unlock()
+
+	if suppressed > 0 {
+		// the event is shared by the channels, change a copy
+		c := *e
+		c.Details = append(slices.Clone(e.Details), fmt.Sprintf("%d event(s) suppressed by rate limit", suppressed))
+		e = &c
+	}
+	return t.Next.Send(e)
+}
+
+func (t *Throttle) Close() error {
+	return closeNotify(t.Next)
+}
+
+// Digest batches the events below the error severity and sends a summary every interval, the errors and reports are
+// sent at once
+type Digest struct {
+	Next     Notify
+	Interval time.Duration
+
+	mu        sync.Mutex
+	events    []*Event
+	lastFlush time.Time
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewDigest(next Notify, interval time.Duration) *Digest {
+	d := &Digest{Next: next, Interval: interval, lastFlush: time.Now(), done: make(chan struct{})}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				_ = d.Flush()
+			}
+		}
+	}()
+
+	return d
+}
+
+func (d *Digest) Send(e *Event) error {
+	if e.Severity >= Error || e.Type == EventReport {
+		return d.Next.Send(e)
+	}
+
+	d.mu.Lock()
+	d.events = append(d.events, e)
+	d.mu.Unlock()
+	return nil
+}
+
+// Flush sends the summary of the events batched since the last flush, which is shorter than the interval on close
+func (d *Digest) Flush() error {
+	now := time.Now()
+
+	d.mu.Lock()
+	events := d.events
+	period := now.Sub(d.lastFlush)
+	d.events, d.lastFlush = nil, now
+	d.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	return d.Next.Send(summarize(events, period.Round(time.Second)))
+}
+
+// Close stops the ticker and flushes the batched events
+func (d *Digest) Close() error {
+	close(d.done)
+	d.wg.Wait()
+	return errors.Join(d.Flush(), closeNotify(d.Next))
+}
+
+// summarize groups the events by type and region, e.g. "7 rotation_succeeded in ap-northeast-1: node1, node2"
+func summarize(events []*Event, period time.Duration) *Event {
+	type group struct {
+		eventType EventType
+		region    string
+		nodes     []string
+		count     int
+	}
+
+	groups := make(map[string]*group)
+	severity := Info
+	for _, e := range events {
+		key := string(e.Type) + "|" + e.Region
+		g, ok := groups[key]
+		if !ok {
+			g = &group{eventType: e.Type, region: e.Region}
+			groups[key] = g
+		}
+		g.count++
+		if e.Node != "" && !slices.Contains(g.nodes, e.Node) {
+			g.nodes = append(g.nodes, e.Node)
+		}
+		severity = max(severity, e.Severity)
+	}
+
+	keys := slices.Sorted(maps.Keys(groups))
+	details := make([]string, 0, len(keys))
+	for _, k := range keys {
+		g := groups[k]
+		line := fmt.Sprintf("%d %s", g.count, g.eventType)
+		if g.region != "" {
+			line += " in " + g.region
+		}
+		if len(g.nodes) > 0 {
+			line += ": " + strings.Join(g.nodes, ", ")
+		}
+		details = append(details, line)
+	}
+
+	e := NewEvent(EventDigest)
+	e.Severity = severity
+	e.Node = "Digest"
+	e.Details = details
+	e.Duration = period
+	return e
+}
+
+// closeNotify closes the notifier if it holds resources
+func closeNotify(n Notify) error {
+	if c, ok := n.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordNotify struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (r *recordNotify) Send(e *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recordNotify) sent() []*Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+func rotated(node string, region string) *Event {
+	e := NewEvent(EventRotationSucceeded)
+	e.Node, e.Region, e.NewIP = node, region, "1.1.1.1"
+	return e
+}
+
+func TestDedupe_Send(t *testing.T) {
+	r := &recordNotify{}
+	d := NewDedupe(r, time.Millisecond*50)
+
+	_ = d.Send(rotated("node1", "ap-northeast-1"))
+	_ = d.Send(rotated("node1", "ap-northeast-1"))
+	_ = d.Send(rotated("node2", "ap-northeast-1"))
+	if len(r.sent()) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(r.sent()))
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	_ = d.Send(rotated("node1", "ap-northeast-1"))
+	if len(r.sent()) != 3 {
+		t.Errorf("expected the event to be sent after the window, got %d", len(r.sent()))
+	}
+
+	// the updates of different domains on one node are not identical
+	updated := func(details ...string) *Event {
+		e := NewEvent(EventDDNSUpdated)
+		e.Node, e.Details = "node1", details
+		return e
+	}
+	_ = d.Send(updated("hk1.example.com: 1.1.1.1"))
+	_ = d.Send(updated("hk2.example.com: 1.1.1.1"))
+	_ = d.Send(updated("hk1.example.com: 1.1.1.1"))
+	if len(r.sent()) != 5 {
+		t.Errorf("expected 5 events, got %d", len(r.sent()))
+	}
+}
+
+func TestThrottle_Send(t *testing.T) {
+	r := &recordNotify{}
+	th := NewThrottle(r, 2, time.Millisecond*50)
+
+	first := rotated("node1", "")
+	for _, e := range []*Event{first, rotated("node2", ""), rotated("node3", ""), rotated("node4", "")} {
+		_ = th.Send(e)
+	}
+	if len(r.sent()) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(r.sent()))
+	}
+
+	time.Sleep(time.Millisecond * 60)
+	e := rotated("node5", "")
+	_ = th.Send(e)
+	sent := r.sent()
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(sent))
+	}
+	if last := sent[2]; len(last.Details) != 1 || !strings.HasPrefix(last.Details[0], "2 event(s) suppressed") {
+		t.Errorf("unexpected details: %v", last.Details)
+	}
+	if len(e.Details) != 0 {
+		t.Errorf("the shared event is changed: %v", e.Details)
+	}
+}
+
+func TestDigest_Send(t *testing.T) {
+	r := &recordNotify{}
+	d := NewDigest(r, time.Hour)
+
+	_ = d.Send(rotated("node1", "ap-northeast-1"))
+	_ = d.Send(rotated("node2", "ap-northeast-1"))
+	_ = d.Send(rotated("node3", "us-east-1"))
+	_ = d.Send(NewEvent(EventRotationFailed))
+	if len(r.sent()) != 1 || r.sent()[0].Type != EventRotationFailed {
+		t.Fatalf("expected only the error to be sent at once, got %d", len(r.sent()))
+	}
+
+	// the digest covers the time since the last flush, not the whole interval
+	d.mu.Lock()
+	d.lastFlush = time.Now().Add(-time.Minute * 5)
+	d.mu.Unlock()
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sent := r.sent()
+	if len(sent) != 2 || sent[1].Type != EventDigest {
+		t.Fatalf("expected a digest, got %d", len(sent))
+	}
+	if sent[1].Duration != time.Minute*5 {
+		t.Errorf("unexpected digest duration: %s", sent[1].Duration)
+	}
+	want := []string{
+		"2 rotation_succeeded in ap-northeast-1: node1, node2",
+		"1 rotation_succeeded in us-east-1: node3",
+	}
+	if strings.Join(sent[1].Details, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected digest: %q", sent[1].Details)
+	}
+
+	_, content, err := DefaultTemplates.Render(sent[1])
+	if err != nil || !strings.Contains(content, want[0]) {
+		t.Errorf("unexpected content: %q, %v", content, err)
+	}
+}
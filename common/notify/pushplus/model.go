@@ -1,7 +1,44 @@
 package pushplus
 
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+var (
+	// ErrUnauthorized is returned when the token is invalid or the request is not allowed
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrLimited is returned when the account is restricted or out of credits
+	ErrLimited = errors.New("limited")
+	// ErrServer is returned when PushPlus fails to handle the request, it may succeed later
+	ErrServer = errors.New("server error")
+)
+
 type PushPlus struct {
-	Token string
+	Api         string // the send api, default: https://www.pushplus.plus/send
+	Token       string
+	Topic       string // the group code, the message is sent to the group members
+	Template    string // html, txt, markdown or json, default: html
+	Channel     string // wechat, webhook, cp, mail or sms, default: wechat
+	WebhookCode string // the webhook code of the webhook and cp channels
+	Templates   *notify.Templates
+
+	mu           sync.Mutex
+	limitedUntil time.Time
+}
+
+type sendReq struct {
+	Token    string `json:"token"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Topic    string `json:"topic,omitempty"`
+	Template string `json:"template,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	Webhook  string `json:"webhook,omitempty"`
 }
 
 type pushPlusResp struct {
@@ -9,3 +46,43 @@ type pushPlusResp struct {
 	Msg  string `json:"msg"`
 	Data string `json:"data"`
 }
+
+// Error is an error returned by PushPlus, it wraps ErrUnauthorized, ErrLimited or ErrServer by the code
+type Error struct {
+	Code int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[PushPlus] %d: %s", e.Code, e.Msg)
+}
+
+// DecodeError is returned when the body is not a PushPlus response, it wraps ErrServer on a server failure status
+type DecodeError struct {
+	Status int
+	Body   string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("[PushPlus] unexpected response %d: %s", e.Status, e.Body)
+}
+
+func (e *DecodeError) Unwrap() error {
+	if e.Status >= 500 {
+		return ErrServer
+	}
+	return nil
+}
+
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case 302, 401, 403, 903:
+		return ErrUnauthorized
+	case 888, 900, 905:
+		return ErrLimited
+	case 500, 502, 503, 504, 999:
+		return ErrServer
+	default:
+		return nil
+	}
+}
@@ -1,29 +1,184 @@
 package pushplus
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const defaultApi = "https://www.pushplus.plus/send"
+
+var (
+	// retryInterval is the time to wait before retrying a server failure
+	retryInterval = time.Second * 5
+	// limitBackoff is the time to stop sending after the account is limited, retrying only wastes the quota
+	limitBackoff = time.Minute * 10
 )
 
-func (p *PushPlus) Webhook(title string, content string) error {
-	api := "https://www.pushplus.plus/send/"
+func New(c map[string]string, templates *notify.Templates) (*PushPlus, error) {
+	p := &PushPlus{
+		Api:         c[strings.ToLower("PUSHPLUS_API")],
+		Token:       c[strings.ToLower("PUSHPLUS_TOKEN")],
+		Topic:       c[strings.ToLower("PUSHPLUS_TOPIC")],
+		Template:    strings.ToLower(c[strings.ToLower("PUSHPLUS_TEMPLATE")]),
+		Channel:     strings.ToLower(c[strings.ToLower("PUSHPLUS_CHANNEL")]),
+		WebhookCode: c[strings.ToLower("PUSHPLUS_WEBHOOK")],
+		Templates:   templates,
+	}
+	if p.Token == "" {
+		return nil, fmt.Errorf("pushplus token is empty")
+	}
+
+	switch p.Template {
+	case "", "html", "txt", "markdown", "json":
+	default:
+		return nil, fmt.Errorf("not support pushplus template: %s", p.Template)
+	}
+	switch p.Channel {
+	case "", "wechat", "mail", "sms":
+	case "webhook", "cp":
+		if p.WebhookCode == "" {
+			return nil, fmt.Errorf("pushplus %s channel needs the webhook code", p.Channel)
+		}
+	default:
+		return nil, fmt.Errorf("not support pushplus channel: %s", p.Channel)
+	}
+
+	return p, nil
+}
+
+// Send renders the event and pushes it with the event fields in the format of the template
+func (p *PushPlus) Send(e *notify.Event) error {
+	title, content, err := p.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	if content, err = p.format(content, e.Fields()); err != nil {
+		return err
+	}
+	return p.push(title, content)
+}
+
+// format builds the content of the template type
+func (p *PushPlus) format(content string, fields []notify.Field) (string, error) {
+	var b strings.Builder
+	switch p.Template {
+	case "json":
+		// the json template renders the object as a table
+		m := map[string]string{"Message": content}
+		for _, f := range fields {
+			m[f.Name] = f.Value
+		}
+		j, err := json.Marshal(m)
+		return string(j), err
+	case "markdown":
+		b.WriteString(content)
+		if len(fields) > 0 {
+			b.WriteString("\n")
+		}
+		for _, f := range fields {
+			fmt.Fprintf(&b, "\n- **%s**: %s", f.Name, f.Value)
+		}
+	case "txt":
+		b.WriteString(content)
+		for _, f := range fields {
+			fmt.Fprintf(&b, "\n%s: %s", f.Name, f.Value)
+		}
+	default:
+		b.WriteString(strings.ReplaceAll(html.EscapeString(content), "\n", "<br/>"))
+		if len(fields) > 0 {
+			b.WriteString("<table>")
+			for _, f := range fields {
+				fmt.Fprintf(&b, "<tr><td><b>%s</b></td><td>%s</td></tr>", html.EscapeString(f.Name), html.EscapeString(f.Value))
+			}
+			b.WriteString("</table>")
+		}
+	}
+
+	return b.String(), nil
+}
+
+// push sends the message, the network and server failures are retried 3 times. An unauthorized request or an
+// unexpected response is not retried, and a limited account stops the sending for a while.
+func (p *PushPlus) push(title string, content string) error {
+	p.mu.Lock()
+	limitedUntil := p.limitedUntil
+	p.mu.Unlock()
+	if time.Now().Before(limitedUntil) {
+		return fmt.Errorf("[PushPlus] skip sending until %s: %w", limitedUntil.Format(time.DateTime), ErrLimited)
+	}
+
+	var err error
+	for i := 0; i < 3; i++ {
+		if err = p.post(title, content); err == nil {
+			return nil
+		}
+
+		if errors.Is(err, ErrLimited) {
+			p.mu.Lock()
+			p.limitedUntil = time.Now().Add(limitBackoff)
+			p.mu.Unlock()
+			return err
+		}
+		if permanent(err) {
+			return err
+		}
+
+		log.Warnf("%v, attempt retry..(%d/3)", err, i+1)
+		time.Sleep(retryInterval)
+	}
+
+	return err
+}
+
+func (p *PushPlus) post(title string, content string) error {
+	api := p.Api
+	if api == "" {
+		api = defaultApi
+	}
+
 	rtn := &pushPlusResp{}
-	resp, err := resty.New().SetRetryCount(3).R().SetResult(rtn).SetBody(map[string]string{
-		"token":   p.Token,
-		"title":   title,
-		"content": content,
-	}).ForceContentType("application/json").Post(api)
+	resp, err := resty.New().SetTimeout(time.Second * 10).R().
+		SetResult(rtn).
+		SetError(rtn).
+		SetBody(&sendReq{
+			Token:    p.Token,
+			Title:    title,
+			Content:  content,
+			Topic:    p.Topic,
+			Template: p.Template,
+			Channel:  p.Channel,
+			Webhook:  p.WebhookCode,
+		}).
+		ForceContentType("application/json").
+		Post(api)
 	if err != nil {
 		return err
 	}
 
 	switch rtn.Code {
-	case 0:
-		return fmt.Errorf("[PushPlus] %s", resp.String())
 	case 200:
 		return nil
+	case 0:
+		// the body is not a PushPlus response
+		return &DecodeError{Status: resp.StatusCode(), Body: resp.String()}
 	default:
-		return fmt.Errorf("[PushPlus] %s", rtn.Msg)
+		return &Error{Code: rtn.Code, Msg: rtn.Msg}
 	}
 }
+
+// permanent reports whether the PushPlus failure will not go away on retry
+func permanent(err error) bool {
+	_, isErr := errors.AsType[*Error](err)
+	_, isDecode := errors.AsType[*DecodeError](err)
+	return (isErr || isDecode) && !errors.Is(err, ErrServer)
+}
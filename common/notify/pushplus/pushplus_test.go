@@ -0,0 +1,163 @@
+package pushplus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakePushPlus struct {
+	reqs    []sendReq
+	calls   int
+	replies []string // the replies before the normal handling
+	status  int      // the status of the replies, default: 200
+}
+
+func (f *fakePushPlus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls++
+	if len(f.replies) > 0 {
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+		}
+		_, _ = w.Write([]byte(f.replies[0]))
+		f.replies = f.replies[1:]
+		return
+	}
+
+	req := sendReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	if req.Token != "token" {
+		_, _ = w.Write([]byte(`{"code":903,"msg":"无效的用户token","data":null}`))
+		return
+	}
+
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"code":200,"msg":"请求成功","data":"1"}`))
+}
+
+func TestPushPlus_Send(t *testing.T) {
+	f := &fakePushPlus{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	p, err := New(map[string]string{"pushplus_api": srv.URL, "pushplus_token": "token", "pushplus_topic": "ops", "pushplus_template": "json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventRotationSucceeded)
+	e.Node, e.Region, e.OldIP, e.NewIP = "node1.test.com(tcp4)", "ap-northeast-1", "1.1.1.1", "2.2.2.2"
+	if err := p.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Topic != "ops" || f.reqs[0].Template != "json" {
+		t.Fatalf("unexpected requests: %+v", f.reqs)
+	}
+	content := map[string]string{}
+	if err := json.Unmarshal([]byte(f.reqs[0].Content), &content); err != nil {
+		t.Fatal(err)
+	}
+	if content["Message"] != "IP changed: 2.2.2.2" || content["Old IP"] != "1.1.1.1" || content["Region"] != "ap-northeast-1" {
+		t.Errorf("unexpected content: %v", content)
+	}
+
+	p.Token = "wrong"
+	err = p.Send(notify.NewEvent(notify.EventServiceStarted))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestPushPlus_format(t *testing.T) {
+	fields := []notify.Field{{Name: "Node", Value: "<node1>"}}
+
+	p := &PushPlus{}
+	if s, _ := p.format("a\nb", fields); s != "a<br/>b<table><tr><td><b>Node</b></td><td>&lt;node1&gt;</td></tr></table>" {
+		t.Errorf("unexpected html: %s", s)
+	}
+	p.Template = "markdown"
+	if s, _ := p.format("a", fields); !strings.HasSuffix(s, "\n- **Node**: <node1>") {
+		t.Errorf("unexpected markdown: %s", s)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(map[string]string{"pushplus_token": "token", "pushplus_channel": "webhook"}, nil); err == nil {
+		t.Error("expected webhook code error")
+	}
+	if _, err := New(map[string]string{"pushplus_token": "token", "pushplus_template": "xml"}, nil); err == nil {
+		t.Error("expected template error")
+	}
+}
+
+func TestPushPlus_Retry(t *testing.T) {
+	interval := retryInterval
+	retryInterval = 0
+	t.Cleanup(func() { retryInterval = interval })
+
+	f := &fakePushPlus{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	p, err := New(map[string]string{"pushplus_api": srv.URL, "pushplus_token": "token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := notify.NewEvent(notify.EventServiceStarted)
+
+	// the server failure is retried
+	f.replies = []string{`{"code":500,"msg":"系统异常"}`}
+	if err := p.Send(e); err != nil || f.calls != 2 {
+		t.Errorf("expected retry success: %v %d", err, f.calls)
+	}
+
+	// the unauthorized request is not retried
+	p.Token = "wrong"
+	if err := p.Send(e); !errors.Is(err, ErrUnauthorized) || f.calls != 3 {
+		t.Errorf("expected unauthorized without retry: %v %d", err, f.calls)
+	}
+
+	// the limited account stops sending
+	p.Token = "token"
+	f.replies = []string{`{"code":900,"msg":"用户账号使用受限"}`}
+	if err := p.Send(e); !errors.Is(err, ErrLimited) || f.calls != 4 {
+		t.Errorf("expected limited: %v %d", err, f.calls)
+	}
+	if err := p.Send(e); !errors.Is(err, ErrLimited) || f.calls != 4 {
+		t.Errorf("expected the sending to be skipped: %v %d", err, f.calls)
+	}
+}
+
+func TestPushPlus_Decode(t *testing.T) {
+	interval := retryInterval
+	retryInterval = 0
+	t.Cleanup(func() { retryInterval = interval })
+
+	f := &fakePushPlus{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	p, err := New(map[string]string{"pushplus_api": srv.URL, "pushplus_token": "token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := notify.NewEvent(notify.EventServiceStarted)
+
+	// the unexpected response of a server failure is retried
+	f.status, f.replies = http.StatusBadGateway, []string{"<html>502 Bad Gateway</html>"}
+	if err := p.Send(e); err != nil || f.calls != 2 {
+		t.Errorf("expected retry success: %v %d", err, f.calls)
+	}
+
+	// any other unexpected response is not
+	f.status, f.replies = http.StatusNotFound, []string{"404 page not found"}
+	err = p.Send(e)
+	if _, ok := errors.AsType[*DecodeError](err); !ok || errors.Is(err, ErrServer) || f.calls != 3 {
+		t.Errorf("expected decode error without retry: %v %d", err, f.calls)
+	}
+}
@@ -0,0 +1,32 @@
+package slack
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type Slack struct {
+	WebhookURL string
+	Templates  *notify.Templates
+}
+
+type webhookReq struct {
+	Text        string       `json:"text"`
+	Attachments []attachment `json:"attachments,omitempty"`
+}
+
+// attachment carries the blocks, it is the only way to color a message
+type attachment struct {
+	Color  string  `json:"color"`
+	Blocks []block `json:"blocks"`
+}
+
+type block struct {
+	Type   string  `json:"type"`
+	Text   *text   `json:"text,omitempty"`
+	Fields []*text `json:"fields,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
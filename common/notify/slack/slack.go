@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// maxRetryAfter caps the wait of a rate limited request
+const maxRetryAfter = time.Minute
+
+// retryInterval is the time to wait before retrying a failed request
+var retryInterval = time.Second * 5
+
+// severityColors are the attachment colors of the severities
+var severityColors = map[notify.Severity]string{
+	notify.Info:    "#2eb67d",
+	notify.Warning: "#ecb22e",
+	notify.Error:   "#e01e5a",
+}
+
+// mrkdwnEscaper escapes the control characters of mrkdwn
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func New(c map[string]string, templates *notify.Templates) (*Slack, error) {
+	s := &Slack{
+		WebhookURL: c[strings.ToLower("SLACK_WEBHOOK_URL")],
+		Templates:  templates,
+	}
+	if s.WebhookURL == "" {
+		return nil, errors.New("slack webhook url is empty")
+	}
+
+	return s, nil
+}
+
+// Send posts the event as blocks in an attachment colored by severity
+func (s *Slack) Send(e *notify.Event) error {
+	title, content, err := s.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	blocks := []block{
+		{Type: "header", Text: &text{Type: "plain_text", Text: title}},
+		{Type: "section", Text: &text{Type: "mrkdwn", Text: mrkdwnEscaper.Replace(content)}},
+	}
+	if fields := e.Fields(); len(fields) > 0 {
+		b := block{Type: "section"}
+		for _, f := range fields {
+			b.Fields = append(b.Fields, &text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", f.Name, mrkdwnEscaper.Replace(f.Value))})
+		}
+		blocks = append(blocks, b)
+	}
+
+	return s.post(&webhookReq{
+		Text:        title + "\n" + content,
+		Attachments: []attachment{{Color: severityColors[e.Severity], Blocks: blocks}},
+	})
+}
+
+// post posts the message, the rate limited and server failures are retried 3 times
+func (s *Slack) post(req *webhookReq) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		var resp *resty.Response
+		resp, err = resty.New().SetTimeout(time.Second * 10).R().SetBody(req).Post(s.WebhookURL)
+
+		wait := retryInterval
+		switch {
+		case err != nil:
+		case resp.IsSuccess():
+			return nil
+		case resp.StatusCode() == http.StatusTooManyRequests:
+			err = errors.New("[Slack] rate limited")
+			if after := notify.RetryAfter(resp.Header().Get("Retry-After"), maxRetryAfter); after > 0 {
+				wait = after
+			}
+		case resp.StatusCode() >= 500:
+			err = fmt.Errorf("[Slack] %d: %s", resp.StatusCode(), resp.String())
+		default:
+			// the webhook replies the error name, e.g. invalid_payload, no_service
+			return fmt.Errorf("[Slack] %d: %s", resp.StatusCode(), resp.String())
+		}
+
+		log.Warnf("%v, attempt retry..(%d/3)", err, i+1)
+		time.Sleep(wait)
+	}
+
+	return err
+}
@@ -0,0 +1,78 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeSlack struct {
+	reqs    []webhookReq
+	limited int
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/services/T/B/token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("invalid_token"))
+		return
+	}
+	if f.limited > 0 {
+		f.limited--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	req := webhookReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func newTestSlack(t *testing.T, f *fakeSlack, path string) *Slack {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	retryInterval = 0
+
+	s, err := New(map[string]string{"slack_webhook_url": srv.URL + path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSlack_Send(t *testing.T) {
+	f := &fakeSlack{limited: 1}
+	s := newTestSlack(t, f, "/services/T/B/token")
+
+	e := notify.NewEvent(notify.EventNodeBlocked)
+	e.Node, e.Network, e.IP, e.Error = "node1.test.com(tcp4)", "tcp4", "1.1.1.1", "dial tcp4 1.1.1.1:8080: i/o timeout <x>"
+	if err := s.Send(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.reqs) != 1 || len(f.reqs[0].Attachments) != 1 {
+		t.Fatalf("unexpected requests: %+v", f.reqs)
+	}
+	a := f.reqs[0].Attachments[0]
+	if a.Color != severityColors[notify.Warning] || len(a.Blocks) != 3 || a.Blocks[0].Text.Text != "node1.test.com(tcp4)" {
+		t.Errorf("unexpected attachment: %+v", a)
+	}
+	if got := a.Blocks[1].Text.Text; got != "Connection blocked: 1.1.1.1\ndial tcp4 1.1.1.1:8080: i/o timeout &lt;x&gt;" {
+		t.Errorf("unexpected content: %q", got)
+	}
+	if len(a.Blocks[2].Fields) != 3 || a.Blocks[2].Fields[2].Text != "*IP*\n1.1.1.1" {
+		t.Errorf("unexpected fields: %+v", a.Blocks[2].Fields)
+	}
+}
+
+func TestSlack_Error(t *testing.T) {
+	s := newTestSlack(t, &fakeSlack{}, "/services/T/B/wrong")
+	if err := s.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[Slack] 403: invalid_token" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package smtp
+
+import (
+	"crypto/tls"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type SMTP struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	To        []string
+	Security  string // starttls, tls (implicit) or none
+	Auth      string // plain or login
+	TLSConfig *tls.Config
+	Templates *notify.Templates
+}
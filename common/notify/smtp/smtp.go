@@ -0,0 +1,263 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const timeout = time.Second * 30
+
+// defaultPorts are the submission ports of the security modes
+var defaultPorts = map[string]int{
+	"starttls": 587,
+	"tls":      465,
+	"none":     25,
+}
+
+func New(c map[string]string, templates *notify.Templates) (*SMTP, error) {
+	s := &SMTP{
+		Host:      c[strings.ToLower("SMTP_HOST")],
+		Username:  c[strings.ToLower("SMTP_USERNAME")],
+		Password:  c[strings.ToLower("SMTP_PASSWORD")],
+		From:      c[strings.ToLower("SMTP_FROM")],
+		Security:  strings.ToLower(c[strings.ToLower("SMTP_SECURITY")]),
+		Auth:      strings.ToLower(c[strings.ToLower("SMTP_AUTH")]),
+		Templates: templates,
+	}
+	for _, to := range strings.Split(c[strings.ToLower("SMTP_TO")], ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			s.To = append(s.To, to)
+		}
+	}
+	if s.Host == "" || len(s.To) == 0 {
+		return nil, errors.New("smtp host or recipients is empty")
+	}
+	if s.From == "" {
+		s.From = s.Username
+	}
+
+	if s.Security == "" {
+		s.Security = "starttls"
+	}
+	if _, ok := defaultPorts[s.Security]; !ok {
+		return nil, fmt.Errorf("not support smtp security: %s", s.Security)
+	}
+	if s.Auth == "" {
+		s.Auth = "plain"
+	}
+	if s.Auth != "plain" && s.Auth != "login" {
+		return nil, fmt.Errorf("not support smtp auth: %s", s.Auth)
+	}
+
+	s.Port = defaultPorts[s.Security]
+	if port := c[strings.ToLower("SMTP_PORT")]; port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("smtp port: %w", err)
+		}
+		s.Port = p
+	}
+
+	return s, nil
+}
+
+// Send mails the event with a plain text and an HTML body
+func (s *SMTP) Send(e *notify.Event) error {
+	title, content, err := s.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h3>%s</h3>\n<p>%s</p>\n", html.EscapeString(title),
+		strings.ReplaceAll(html.EscapeString(content), "\n", "<br>\n"))
+	if fields := e.Fields(); len(fields) > 0 {
+		b.WriteString("<table>\n")
+		for _, f := range fields {
+			fmt.Fprintf(&b, "<tr><th align=\"left\">%s</th><td>%s</td></tr>\n", html.EscapeString(f.Name), html.EscapeString(f.Value))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	text := content
+	for _, f := range e.Fields() {
+		text += fmt.Sprintf("\n%s: %s", f.Name, f.Value)
+	}
+
+	return s.mail(title, text, b.String())
+}
+
+// mail delivers the message to every recipient
+func (s *SMTP) mail(subject string, text string, htmlBody string) error {
+	msg, err := s.message(subject, text, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.dial()
+	if err != nil {
+		return fmt.Errorf("[SMTP] %w", err)
+	}
+	defer c.Close()
+
+	if s.Username != "" {
+		var auth smtp.Auth
+		if s.Auth == "login" {
+			auth = &loginAuth{username: s.Username, password: s.Password}
+		} else {
+			auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+		}
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("[SMTP] auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.From); err != nil {
+		return fmt.Errorf("[SMTP] mail from: %w", err)
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("[SMTP] rcpt to %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("[SMTP] data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("[SMTP] data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("[SMTP] data: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// dial connects to the server and secures the connection by the security mode
+func (s *SMTP) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := s.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: s.Host}
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: timeout}
+	if s.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.Security == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// message builds a multipart/alternative message with the text and HTML bodies
+func (s *SMTP) message(subject string, text string, htmlBody string) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := []string{
+		"From: " + s.From,
+		"To: " + strings.Join(s.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", "[LightsailMon] "+subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// loginAuth implements the LOGIN mechanism, which is only allowed over TLS
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
@@ -0,0 +1,247 @@
+package smtp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// fakeSMTP is a minimal smtp server which accepts the user:pass credentials
+type fakeSMTP struct {
+	t        *testing.T
+	ln       net.Listener
+	tls      *tls.Config
+	implicit bool
+
+	mu    sync.Mutex
+	auth  string
+	rcpts []string
+	data  string
+}
+
+func newFakeSMTP(t *testing.T, implicit bool) (*fakeSMTP, *tls.Config) {
+	cert, pool := newCert(t)
+	f := &fakeSMTP{t: t, tls: &tls.Config{Certificates: []tls.Certificate{cert}}, implicit: implicit}
+
+	var err error
+	if implicit {
+		f.ln, err = tls.Listen("tcp", "127.0.0.1:0", f.tls)
+	} else {
+		f.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.ln.Close() })
+
+	go func() {
+		for {
+			conn, err := f.ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func (f *fakeSMTP) port() int {
+	return f.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), conn
+	reply := func(s string) { _, _ = io.WriteString(w, s+"\r\n") }
+	readLine := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+	decode := func(s string) string {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}
+
+	secure := f.implicit
+	reply("220 fake ESMTP")
+	for {
+		line := readLine()
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO":
+			if !secure {
+				reply("250-fake\r\n250-STARTTLS")
+			}
+			reply("250-fake\r\n250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, f.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			r, w, secure = bufio.NewReader(tlsConn), tlsConn, true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			var user, pass string
+			if mech == "PLAIN" {
+				parts := strings.Split(decode(initial), "\x00")
+				user, pass = parts[1], parts[2]
+			} else {
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				user = decode(readLine())
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				pass = decode(readLine())
+			}
+			if user != "user" || pass != "pass" {
+				reply("535 authentication failed")
+				continue
+			}
+			f.mu.Lock()
+			f.auth = mech
+			f.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			f.mu.Lock()
+			f.rcpts = append(f.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			f.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data []string
+			for l := readLine(); l != "."; l = readLine() {
+				data = append(data, l)
+			}
+			f.mu.Lock()
+			f.data = strings.Join(data, "\r\n")
+			f.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("500 unknown command")
+		}
+	}
+}
+
+// newCert creates a self-signed certificate of 127.0.0.1
+func newCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func newTestSMTP(t *testing.T, security string, auth string) (*SMTP, *fakeSMTP) {
+	f, tlsConfig := newFakeSMTP(t, security == "tls")
+	s, err := New(map[string]string{
+		"smtp_host":     "127.0.0.1",
+		"smtp_port":     strconv.Itoa(f.port()),
+		"smtp_username": "user",
+		"smtp_password": "pass",
+		"smtp_from":     "monitor@test.com",
+		"smtp_to":       "ops@test.com, oncall@test.com",
+		"smtp_security": security,
+		"smtp_auth":     auth,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.TLSConfig = tlsConfig
+	return s, f
+}
+
+func TestSMTP_Send(t *testing.T) {
+	tests := []struct{ security, auth string }{
+		{"starttls", "plain"},
+		{"starttls", "login"},
+		{"tls", "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.security+"_"+tt.auth, func(t *testing.T) {
+			s, f := newTestSMTP(t, tt.security, tt.auth)
+
+			e := notify.NewEvent(notify.EventRotationSucceeded)
+			e.Node, e.OldIP, e.NewIP = "node1.test.com(tcp4)", "1.1.1.1", "2.2.2.2"
+			if err := s.Send(e); err != nil {
+				t.Fatal(err)
+			}
+
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.auth != strings.ToUpper(tt.auth) || len(f.rcpts) != 2 || f.rcpts[1] != "oncall@test.com" {
+				t.Errorf("unexpected session: %s %v", f.auth, f.rcpts)
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(f.data + "\r\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "[LightsailMon] node1.test.com(tcp4)" {
+				t.Errorf("unexpected subject: %s", subject)
+			}
+
+			_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			mr := multipart.NewReader(msg.Body, params["boundary"])
+			var types []string
+			for {
+				p, err := mr.NextPart()
+				if err != nil {
+					break
+				}
+				types = append(types, p.Header.Get("Content-Type"))
+				body, _ := io.ReadAll(p)
+				if !strings.Contains(string(body), "2.2.2.2") {
+					t.Errorf("unexpected body: %s", body)
+				}
+			}
+			if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+				t.Errorf("unexpected parts: %v", types)
+			}
+		})
+	}
+}
+
+func TestSMTP_AuthFailed(t *testing.T) {
+	s, _ := newTestSMTP(t, "starttls", "plain")
+	s.Password = "wrong"
+	if err := s.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || !strings.Contains(err.Error(), "535") {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
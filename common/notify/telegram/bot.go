@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPollTimeout is the long polling timeout of getUpdates
+const defaultPollTimeout = time.Second * 30
+
+// ErrUsage is returned by a command handler on wrong arguments, the command usage is replied
+var ErrUsage = errors.New("wrong arguments")
+
+// Command handles the arguments of a bot command and returns the reply
+type Command struct {
+	Usage   string
+	Handler func(args []string) (string, error)
+}
+
+// Bot receives commands from the allowed chats by long polling, and replies to them
+type Bot struct {
+	Telegram     *Telegram
+	AllowedChats []string
+	PollTimeout  time.Duration
+
+	commands map[string]*Command
+	offset   int64
+}
+
+func NewBot(t *Telegram, allowedChats []string) *Bot {
+	return &Bot{
+		Telegram:     t,
+		AllowedChats: allowedChats,
+		PollTimeout:  defaultPollTimeout,
+		commands:     make(map[string]*Command),
+	}
+}
+
+// Handle registers the command, the name is without the leading slash
+func (b *Bot) Handle(name string, usage string, handler func(args []string) (string, error)) {
+	b.commands[name] = &Command{Usage: usage, Handler: handler}
+}
+
+// Run skips the updates sent before it starts, and polls the new ones until ctx is done
+func (b *Bot) Run(ctx context.Context) {
+	for {
+		err := b.skipPending(ctx)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("[Telegram] skip pending updates: %v", err)
+		if !sleep(ctx, retryInterval) {
+			return
+		}
+	}
+
+	for {
+		if err := b.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("[Telegram] poll updates: %v", err)
+			if !sleep(ctx, retryInterval) {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// skipPending confirms the pending updates without handling them, the commands sent while the service was down are
+// stale, e.g. a /rotate which has been done by hand
+func (b *Bot) skipPending(ctx context.Context) error {
+	// the offset -1 returns the last update only and confirms the ones before it
+	var updates []update
+	if err := b.Telegram.call(ctx, "getUpdates", &getUpdatesReq{
+		Offset:         -1,
+		AllowedUpdates: []string{"message"},
+	}, &updates, time.Second*10); err != nil {
+		return err
+	}
+
+	if len(updates) > 0 {
+		b.offset = updates[len(updates)-1].UpdateID + 1
+		log.Infof("[Telegram] skip the updates before %d", b.offset)
+	}
+	return nil
+}
+
+// sleep waits for d, and reports false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// poll gets the pending updates once and handles their commands
+func (b *Bot) poll(ctx context.Context) error {
+	var updates []update
+	if err := b.Telegram.call(ctx, "getUpdates", &getUpdatesReq{
+		Offset:         b.offset,
+		Timeout:        int(b.PollTimeout.Seconds()),
+		AllowedUpdates: []string{"message"},
+	}, &updates, b.PollTimeout+time.Second*10); err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		b.offset = u.UpdateID + 1
+		if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+			continue
+		}
+
+		chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+		if !slices.Contains(b.AllowedChats, chatID) {
+			log.Warnf("[Telegram] ignore command from chat %s: %s", chatID, u.Message.Text)
+			continue
+		}
+
+		reply := b.handle(u.Message.Text)
+		if err := b.Telegram.sendMessage(&sendMessageReq{
+			ChatID:          chatID,
+			MessageThreadID: u.Message.MessageThreadID,
+			Text:            reply,
+		}); err != nil {
+			log.Errorf("[Telegram] reply to chat %s: %v", chatID, err)
+		}
+	}
+
+	return nil
+}
+
+// handle runs the command of the text and returns the reply
+func (b *Bot) handle(text string) string {
+	fields := strings.Fields(text)
+	// the command may be addressed to the bot, e.g. /status@LightsailMonBot
+	name, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+
+	cmd, ok := b.commands[name]
+	if !ok {
+		return b.help()
+	}
+
+	log.Infof("[Telegram] run command: %s", text)
+	reply, err := cmd.Handler(fields[1:])
+	if errors.Is(err, ErrUsage) {
+		return "Usage: /" + name + " " + cmd.Usage
+	}
+	if err != nil {
+		return fmt.Sprintf("/%s failed: %v", name, err)
+	}
+	return reply
+}
+
+// help lists the registered commands
+func (b *Bot) help() string {
+	names := make([]string, 0, len(b.commands))
+	for name := range b.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{"Commands:"}
+	for _, name := range names {
+		lines = append(lines, strings.TrimSpace("/"+name+" "+b.commands[name].Usage))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeBotAPI struct {
+	updates []update
+	offsets []int64
+	replies []sendMessageReq
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/bottoken/getUpdates":
+		req := getUpdatesReq{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.offsets = append(f.offsets, req.Offset)
+
+		var pending []update
+		for _, u := range f.updates {
+			if u.UpdateID >= req.Offset {
+				pending = append(pending, u)
+			}
+		}
+		// a negative offset returns the updates from the end
+		if req.Offset < 0 && len(f.updates) > 0 {
+			pending = f.updates[max(len(f.updates)+int(req.Offset), 0):]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": pending})
+	case "/bottoken/sendMessage":
+		req := sendMessageReq{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.replies = append(f.replies, req)
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+	}
+}
+
+func newUpdate(id int64, chatID int64, text string) update {
+	m := &message{Text: text}
+	m.Chat.ID = chatID
+	return update{UpdateID: id, Message: m}
+}
+
+func TestBot_Poll(t *testing.T) {
+	f := &fakeBotAPI{updates: []update{
+		newUpdate(10, 123, "/status"),
+		newUpdate(11, 999, "/rotate node1.test.com"),
+		newUpdate(12, 123, "/rotate@LightsailMonBot node1.test.com"),
+		newUpdate(13, 123, "hello"),
+		newUpdate(14, 123, "/unknown"),
+	}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	b := NewBot(&Telegram{ApiHost: srv.URL, Token: "token"}, []string{"123"})
+	b.PollTimeout = 0
+
+	var rotated []string
+	b.Handle("status", "", func(args []string) (string, error) {
+		return "all good", nil
+	})
+	b.Handle("rotate", "<domain>", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", ErrUsage
+		}
+		rotated = append(rotated, args[0])
+		return "rotating " + args[0], nil
+	})
+
+	if err := b.poll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 || rotated[0] != "node1.test.com" {
+		t.Errorf("the commands of other chats must be ignored: %v", rotated)
+	}
+	if len(f.replies) != 3 || f.replies[0].Text != "all good" || f.replies[1].Text != "rotating node1.test.com" ||
+		!strings.Contains(f.replies[2].Text, "/rotate <domain>") || f.replies[0].ChatID != "123" {
+		t.Errorf("unexpected replies: %+v", f.replies)
+	}
+
+	// the handled updates are confirmed by the next offset
+	if err := b.poll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if f.offsets[1] != 15 || len(f.replies) != 3 {
+		t.Errorf("unexpected offsets: %v", f.offsets)
+	}
+}
+
+func TestBot_SkipPending(t *testing.T) {
+	f := &fakeBotAPI{updates: []update{
+		newUpdate(10, 123, "/rotate node1.test.com"),
+		newUpdate(11, 123, "/status"),
+	}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	b := NewBot(&Telegram{ApiHost: srv.URL, Token: "token"}, []string{"123"})
+	b.PollTimeout = 0
+	b.Handle("rotate", "<domain>", func(args []string) (string, error) {
+		t.Errorf("stale command is handled: %v", args)
+		return "", nil
+	})
+
+	if err := b.skipPending(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.poll(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if b.offset != 12 || len(f.replies) != 0 {
+		t.Errorf("unexpected offset %d, replies: %+v", b.offset, f.replies)
+	}
+}
@@ -1,7 +1,71 @@
 package telegram
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
 type Telegram struct {
-	ApiHost string
-	ChatID  string
-	Token   string
+	ApiHost     string
+	ChatID      string
+	Token       string
+	ParseMode   string // MarkdownV2, HTML or empty for plain text
+	ThreadID    int    // the forum topic of the chat
+	SilentBelow notify.Severity
+	Templates   *notify.Templates
+}
+
+type sendMessageReq struct {
+	ChatID              string `json:"chat_id"`
+	MessageThreadID     int    `json:"message_thread_id,omitempty"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+}
+
+type botResp struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// apiError is an error returned by the bot api
+type apiError struct {
+	Code        int
+	Description string
+	RetryAfter  int
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("[Telegram] %d: %s", e.Code, e.Description)
+}
+
+// temporary reports whether the request may succeed later
+func (e *apiError) temporary() bool {
+	return e.Code == 429 || e.Code >= 500
+}
+
+type getUpdatesReq struct {
+	Offset         int64    `json:"offset,omitempty"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	MessageThreadID int    `json:"message_thread_id"`
+	Text            string `json:"text"`
+	Chat            struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
 }
@@ -1,31 +1,127 @@
 package telegram
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const (
+	defaultApiHost = "api.telegram.org"
+	// maxRetryAfter caps the flood wait of a rate limited request
+	maxRetryAfter = time.Minute
 )
 
-func (t *Telegram) Webhook(title string, content string) error {
-	api := fmt.Sprintf("https://%s/bot%s/sendMessage", t.ApiHost, t.Token)
+// retryInterval is the time to wait before retrying a failed request
+var retryInterval = time.Second * 5
+
+// markdownV2Escaper escapes the reserved characters of MarkdownV2
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// Send renders the event and sends it to the chat, the events below SilentBelow are sent without sound
+func (t *Telegram) Send(e *notify.Event) error {
+	title, content, err := t.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+	return t.send(t.format(title, content), e.Severity < t.SilentBelow)
+}
+
+// format builds the message text in the parse mode, the title and content are escaped
+func (t *Telegram) format(title string, content string) string {
+	switch t.ParseMode {
+	case "MarkdownV2":
+		return fmt.Sprintf("*\\#LightsailMon*\nNode: `%s`\n%s", markdownV2Escaper.Replace(title), markdownV2Escaper.Replace(content))
+	case "HTML":
+		return fmt.Sprintf("<b>#LightsailMon</b>\nNode: <code>%s</code>\n%s", html.EscapeString(title), html.EscapeString(content))
+	default:
+		return fmt.Sprintf("#LightsailMon\nNode: %s\n%s", title, content)
+	}
+}
 
+// send sends the text to the configured chat
+func (t *Telegram) send(text string, silent bool) error {
+	return t.sendMessage(&sendMessageReq{
+		ChatID:              t.ChatID,
+		MessageThreadID:     t.ThreadID,
+		Text:                text,
+		ParseMode:           t.ParseMode,
+		DisableNotification: silent,
+	})
+}
+
+// sendMessage sends the message, the rate limited and server failures are retried 3 times
+func (t *Telegram) sendMessage(req *sendMessageReq) error {
+	var err error
 	for i := 0; i < 3; i++ {
-		_, err := resty.New().SetRetryCount(3).R().SetBody(map[string]any{
-			"chat_id": t.ChatID,
-			"text": fmt.Sprintf("#LightsailMon\nNode: %s\n%s",
-				title,
-				content,
-			),
-		}).Post(api)
-		if err == nil {
-			break
+		if err = t.call(context.Background(), "sendMessage", req, nil, time.Second*10); err == nil {
+			return nil
 		}
 
-		log.Debugf("[telegram] %v, attempt retry..(%d/3)", err, i+1)
-		time.Sleep(time.Second * 5)
+		wait := retryInterval
+		if apiErr, ok := errors.AsType[*apiError](err); ok {
+			if !apiErr.temporary() {
+				return err
+			}
+			if apiErr.RetryAfter > 0 {
+				wait = min(time.Duration(apiErr.RetryAfter)*time.Second, maxRetryAfter)
+			}
+		}
+
+		log.Warnf("%v, attempt retry..(%d/3)", err, i+1)
+		time.Sleep(wait)
+	}
+
+	return err
+}
+
+// call calls the bot api method, and decodes the result into result if it is not nil
+func (t *Telegram) call(ctx context.Context, method string, req any, result any, timeout time.Duration) error {
+	rtn := &botResp{}
+	resp, err := resty.New().SetTimeout(timeout).R().
+		SetContext(ctx).
+		SetBody(req).
+		SetResult(rtn).
+		SetError(rtn).
+		ForceContentType("application/json").
+		Post(t.apiURL(method))
+	if err != nil {
+		return err
+	}
+
+	if !rtn.Ok {
+		if rtn.ErrorCode == 0 {
+			return &apiError{Code: resp.StatusCode(), Description: resp.String()}
+		}
+		return &apiError{Code: rtn.ErrorCode, Description: rtn.Description, RetryAfter: rtn.Parameters.RetryAfter}
+	}
+	if result != nil {
+		return json.Unmarshal(rtn.Result, result)
 	}
 
 	return nil
 }
+
+// apiURL returns the bot api url of the method, the host may carry its own scheme
+func (t *Telegram) apiURL(method string) string {
+	host := t.ApiHost
+	if host == "" {
+		host = defaultApiHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(host, "/"), t.Token, method)
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeBot struct {
+	reqs    []sendMessageReq
+	replies []string
+}
+
+func (f *fakeBot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bottoken/sendMessage" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+		return
+	}
+
+	req := sendMessageReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+
+	reply := `{"ok":true,"result":{}}`
+	if len(f.replies) > 0 {
+		reply, f.replies = f.replies[0], f.replies[1:]
+	}
+	var code struct {
+		ErrorCode int `json:"error_code"`
+	}
+	_ = json.Unmarshal([]byte(reply), &code)
+	if code.ErrorCode != 0 {
+		w.WriteHeader(code.ErrorCode)
+	}
+	_, _ = w.Write([]byte(reply))
+}
+
+func newTestTelegram(t *testing.T, f *fakeBot) *Telegram {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	retryInterval = 0
+	return &Telegram{ApiHost: srv.URL, ChatID: "123", Token: "token"}
+}
+
+func TestTelegram_Send(t *testing.T) {
+	f := &fakeBot{}
+	tg := newTestTelegram(t, f)
+	tg.ParseMode, tg.ThreadID, tg.SilentBelow = "MarkdownV2", 7, notify.Warning
+
+	e := notify.NewEvent(notify.EventRotationSucceeded)
+	e.Node, e.NewIP = "node1.test.com(tcp4)", "1.2.3.4"
+	if err := tg.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 {
+		t.Fatalf("unexpected requests: %+v", f.reqs)
+	}
+	req := f.reqs[0]
+	if req.MessageThreadID != 7 || !req.DisableNotification || req.ParseMode != "MarkdownV2" ||
+		!strings.Contains(req.Text, `node1\.test\.com\(tcp4\)`) || !strings.Contains(req.Text, `1\.2\.3\.4`) {
+		t.Errorf("unexpected request: %+v", req)
+	}
+
+	// the warning events make a sound
+	if err := tg.Send(notify.NewEvent(notify.EventNodeBlocked)); err != nil || f.reqs[1].DisableNotification {
+		t.Errorf("unexpected request: %+v %v", f.reqs[1], err)
+	}
+}
+
+func TestTelegram_Errors(t *testing.T) {
+	f := &fakeBot{replies: []string{
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 0","parameters":{"retry_after":0}}`,
+		`{"ok":true,"result":{}}`,
+		`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`,
+	}}
+	tg := newTestTelegram(t, f)
+
+	// the rate limited request is retried
+	if err := tg.Send(notify.NewEvent(notify.EventServiceStarted)); err != nil || len(f.reqs) != 2 {
+		t.Errorf("expected retry success: %v %d", err, len(f.reqs))
+	}
+
+	// the bad request is not retried
+	if err := tg.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || !strings.Contains(err.Error(), "chat not found") || len(f.reqs) != 3 {
+		t.Errorf("expected chat error: %v %d", err, len(f.reqs))
+	}
+
+	tg.Token = "wrong"
+	if err := tg.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil {
+		t.Error("expected token error")
+	}
+}
+
+func TestTelegram_ApiURL(t *testing.T) {
+	tg := &Telegram{Token: "token"}
+	if u := tg.apiURL("sendMessage"); u != "https://api.telegram.org/bottoken/sendMessage" {
+		t.Errorf("unexpected url: %s", u)
+	}
+	tg.ApiHost = "proxy.test.com"
+	if u := tg.apiURL("sendMessage"); u != "https://proxy.test.com/bottoken/sendMessage" {
+		t.Errorf("unexpected url: %s", u)
+	}
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// titleKey is the template key of the message title
+const titleKey = "title"
+
+var defaultTemplates = map[string]string{
+	titleKey:                       "{{.Node}}",
+	string(EventNodeBlocked):       "Connection blocked: {{.IP}}{{with .Error}}\n{{.}}{{end}}",
+	string(EventRotationStarted):   "Change node IP: {{.IP}}",
+	string(EventRotationSucceeded): "IP changed: {{.NewIP}}{{if .Latency}} ({{ms .Latency}} ms){{end}}{{range .Details}}\n{{.}}{{end}}",
+	string(EventRotationFailed):    "Connection block after IP refresh {{.Attempts}} times: {{.NewIP}}",
+	string(EventDDNSUpdated):       "DNS records updated: {{.IP}}{{range .Details}}\n{{.}}{{end}}",
+	string(EventDDNSFailed):        "DNS records update failed: {{.Error}}",
+	string(EventNetworkDown):       "Monitor host is offline, local {{.Network}} network is down: {{.Error}}",
+	string(EventNetworkUp):         "Monitor host is back online, local {{.Network}} network was down for {{.Duration}}",
+	string(EventCircuitBreaker):    "Circuit breaker tripped: {{.Error}}",
+	string(EventServiceStarted):    "Service started",
+	string(EventServiceStopped):    "Service stopped",
+	string(EventReport):            "Report of the last {{.Duration}}{{range .Details}}\n\n{{.}}{{end}}",
+	string(EventDigest):            "{{len .Details}} kind(s) of events in the last {{.Duration}}{{range .Details}}\n{{.}}{{end}}",
+}
+
+// TemplateFuncs are the helpers available in the templates
+var TemplateFuncs = template.FuncMap{
+	"ms":    func(d time.Duration) int64 { return d.Milliseconds() },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"time":  func(t time.Time, layout string) string { return t.Format(layout) },
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Templates renders events to the message title and content
+type Templates struct {
+	tmpl *template.Template
+}
+
+// NewTemplates parses the default templates, and the overrides keyed by "title" or the event type
+func NewTemplates(overrides map[string]string) (*Templates, error) {
+	tmpl := template.New("").Funcs(TemplateFuncs)
+	for name, text := range defaultTemplates {
+		if o, ok := overrides[name]; ok {
+			text = o
+		}
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("parse %s template: %w", name, err)
+		}
+	}
+	for name := range overrides {
+		if _, ok := defaultTemplates[name]; !ok {
+			return nil, fmt.Errorf("unknown template: %s", name)
+		}
+	}
+
+	return &Templates{tmpl: tmpl}, nil
+}
+
+// DefaultTemplates are the built-in templates
+var DefaultTemplates = func() *Templates {
+	t, err := NewTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+// Render renders the event title and content
+func (t *Templates) Render(e *Event) (string, string, error) {
+	if t == nil {
+		t = DefaultTemplates
+	}
+
+	title, err := t.execute(titleKey, e)
+	if err != nil {
+		return "", "", err
+	}
+	content, err := t.execute(string(e.Type), e)
+	if err != nil {
+		return "", "", err
+	}
+
+	return title, content, nil
+}
+
+func (t *Templates) execute(name string, e *Event) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&b, name, e); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
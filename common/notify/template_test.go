@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	e := NewEvent(EventRotationSucceeded)
+	e.Node, e.OldIP, e.NewIP, e.Latency = "node1.test.com(tcp4)", "1.1.1.1", "2.2.2.2", time.Millisecond*120
+	e.Details = []string{"node1.test.com: propagated in 10s"}
+
+	title, content, err := DefaultTemplates.Render(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "node1.test.com(tcp4)" || content != "IP changed: 2.2.2.2 (120 ms)\nnode1.test.com: propagated in 10s" {
+		t.Errorf("unexpected message: %q %q", title, content)
+	}
+
+	tmpl, err := NewTemplates(map[string]string{
+		"title":              "[{{.Severity}}] {{.Node}}",
+		"rotation_succeeded": "{{.OldIP}} -> {{.NewIP}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, content, _ = tmpl.Render(e); title != "[info] node1.test.com(tcp4)" || content != "1.1.1.1 -> 2.2.2.2" {
+		t.Errorf("unexpected message: %q %q", title, content)
+	}
+
+	if _, err := NewTemplates(map[string]string{"ip_changed": "{{.IP}}"}); err == nil {
+		t.Error("expected unknown template error")
+	}
+}
+
+func TestEvent_Fields(t *testing.T) {
+	e := NewEvent(EventReport)
+	e.Node, e.Duration = "LightsailMon", time.Hour*24
+
+	fields := e.Fields()
+	if len(fields) != 2 || fields[1] != (Field{Name: "Duration", Value: "24h0m0s"}) {
+		t.Errorf("unexpected fields: %+v", fields)
+	}
+
+	_, content, err := DefaultTemplates.Render(e)
+	if err != nil || content != "Report of the last 24h0m0s" {
+		t.Errorf("unexpected content: %q, %v", content, err)
+	}
+}
+
+func TestEventTypes(t *testing.T) {
+	for _, typ := range EventTypes {
+		if _, _, err := DefaultTemplates.Render(NewEvent(typ)); err != nil {
+			t.Errorf("%s: %v", typ, err)
+		}
+	}
+
+	if s := EventCircuitBreaker.Severity(); s != Error {
+		t.Errorf("unexpected circuit breaker severity: %s", s)
+	}
+	e := NewEvent(EventCircuitBreaker).WithError(errors.New("3 nodes blocked in a row"))
+	if _, content, _ := DefaultTemplates.Render(e); content != "Circuit breaker tripped: 3 nodes blocked in a row" {
+		t.Errorf("unexpected content: %q", content)
+	}
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"hash"
+	"text/template"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// Webhook sends the event with a request fully described in config
+type Webhook struct {
+	method    string
+	url       *template.Template
+	headers   map[string]*template.Template
+	body      *template.Template
+	status    []int
+	secret    string
+	hash      func() hash.Hash
+	sigHeader string
+	sigPrefix string
+	templates *notify.Templates
+}
+
+// templateData is the event with its rendered title and content
+type templateData struct {
+	*notify.Event
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
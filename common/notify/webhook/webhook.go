@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+const (
+	// defaultBody posts the whole event as json
+	defaultBody = "{{json .}}"
+	// defaultContentType is sent unless the headers set one
+	defaultContentType = "application/json"
+)
+
+// hashes are the supported hmac algorithms
+var hashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func New(c map[string]string, templates *notify.Templates) (*Webhook, error) {
+	get := func(key string) string {
+		return c[strings.ToLower("WEBHOOK_"+key)]
+	}
+
+	if get("URL") == "" {
+		return nil, errors.New("webhook url is empty")
+	}
+
+	w := &Webhook{
+		method:    strings.ToUpper(get("METHOD")),
+		headers:   make(map[string]*template.Template),
+		secret:    get("HMAC_SECRET"),
+		sigHeader: get("HMAC_HEADER"),
+		sigPrefix: get("HMAC_PREFIX"),
+		templates: templates,
+	}
+	if w.method == "" {
+		w.method = "POST"
+	}
+
+	var err error
+	if w.url, err = parse("url", get("URL")); err != nil {
+		return nil, err
+	}
+	body := get("BODY")
+	if body == "" {
+		body = defaultBody
+	}
+	if w.body, err = parse("body", body); err != nil {
+		return nil, err
+	}
+
+	// headers are written one per line as "Key: Value"
+	for _, line := range strings.Split(get("HEADERS"), "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if w.headers[http.CanonicalHeaderKey(strings.TrimSpace(k))], err = parse(k, strings.TrimSpace(v)); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := w.headers["Content-Type"]; !ok {
+		if w.headers["Content-Type"], err = parse("Content-Type", defaultContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range strings.Split(get("SUCCESS_STATUS"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_SUCCESS_STATUS: %s", s)
+		}
+		w.status = append(w.status, code)
+	}
+
+	if w.secret != "" {
+		algo := strings.ToLower(get("HMAC_ALGO"))
+		if algo == "" {
+			algo = "sha256"
+		}
+		if w.hash = hashes[algo]; w.hash == nil {
+			return nil, fmt.Errorf("not support webhook hmac algorithm: %s", algo)
+		}
+		if w.sigHeader == "" {
+			w.sigHeader = "X-Signature"
+		}
+	}
+
+	return w, nil
+}
+
+// Send renders the request templates over the event and sends it
+func (w *Webhook) Send(e *notify.Event) error {
+	title, content, err := w.templates.Render(e)
+	if err != nil {
+		return err
+	}
+	return w.do(&templateData{Event: e, Title: title, Content: content})
+}
+
+func (w *Webhook) do(data *templateData) error {
+	url, err := render(w.url, data)
+	if err != nil {
+		return err
+	}
+	body, err := render(w.body, data)
+	if err != nil {
+		return err
+	}
+
+	req := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R()
+	for k, h := range w.headers {
+		v, err := render(h, data)
+		if err != nil {
+			return err
+		}
+		req.SetHeader(k, v)
+	}
+	if w.secret != "" {
+		req.SetHeader(w.sigHeader, w.sigPrefix+w.sign([]byte(body)))
+	}
+	if w.method != "GET" && w.method != "HEAD" {
+		req.SetBody(body)
+	}
+
+	resp, err := req.Execute(w.method, url)
+	if err != nil {
+		return err
+	}
+
+	if len(w.status) > 0 && !slices.Contains(w.status, resp.StatusCode()) || len(w.status) == 0 && !resp.IsSuccess() {
+		return fmt.Errorf("[Webhook] %d: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
+
+// sign returns the hex hmac of the body
+func (w *Webhook) sign(body []byte) string {
+	h := hmac.New(w.hash, []byte(w.secret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func parse(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(notify.TemplateFuncs).Parse(text)
+}
+
+func render(t *template.Template, data *templateData) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeAlerting struct {
+	secret      string
+	contentType string
+	paths       []string
+	bodies      []map[string]any
+}
+
+func (f *fakeAlerting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	h := hmac.New(sha256.New, []byte(f.secret))
+	h.Write(body)
+	if r.Header.Get("X-Hub-Signature-256") != "sha256="+hex.EncodeToString(h.Sum(nil)) || r.Header.Get("Authorization") != "Token abc" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Type") != f.contentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	v := map[string]any{}
+	_ = json.Unmarshal(body, &v)
+	f.paths = append(f.paths, r.URL.Path)
+	f.bodies = append(f.bodies, v)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newTestWebhook(t *testing.T, c map[string]string) (*Webhook, *fakeAlerting) {
+	f := &fakeAlerting{secret: "secret", contentType: "application/json"}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	c["webhook_url"] = srv.URL + c["webhook_url"]
+	c["webhook_headers"] = "Authorization: Token abc\n" + c["webhook_headers"]
+	c["webhook_hmac_secret"] = "secret"
+	c["webhook_hmac_header"] = "X-Hub-Signature-256"
+	c["webhook_hmac_prefix"] = "sha256="
+	w, err := New(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, f
+}
+
+func TestWebhook_Send(t *testing.T) {
+	w, f := newTestWebhook(t, map[string]string{
+		"webhook_url":            "/v2/enqueue/{{.Type}}",
+		"webhook_body":           `{"summary":{{json .Content}},"severity":"{{.Severity}}","source":{{json .Node}}}`,
+		"webhook_success_status": "202",
+	})
+
+	e := notify.NewEvent(notify.EventRotationFailed)
+	e.Node, e.NewIP, e.Attempts = "node1.test.com(tcp4)", "2.2.2.2", 3
+	if err := w.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.bodies) != 1 || f.paths[0] != "/v2/enqueue/rotation_failed" {
+		t.Fatalf("unexpected requests: %v %v", f.paths, f.bodies)
+	}
+	if b := f.bodies[0]; b["summary"] != "Connection block after IP refresh 3 times: 2.2.2.2" || b["severity"] != "error" || b["source"] != "node1.test.com(tcp4)" {
+		t.Errorf("unexpected body: %v", b)
+	}
+}
+
+func TestWebhook_DefaultBody(t *testing.T) {
+	w, f := newTestWebhook(t, map[string]string{"webhook_url": "/alerts"})
+
+	e := notify.NewEvent(notify.EventNodeBlocked)
+	e.Node, e.IP = "node1.test.com(tcp4)", "1.1.1.1"
+	if err := w.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if b := f.bodies[0]; b["type"] != "node_blocked" || b["severity"] != "warning" || b["ip"] != "1.1.1.1" || b["content"] != "Connection blocked: 1.1.1.1" {
+		t.Errorf("unexpected body: %v", b)
+	}
+
+	// the signature does not match with another secret
+	w.secret = "wrong"
+	if err := w.Send(e); err == nil {
+		t.Error("expected status error")
+	}
+}
+
+func TestWebhook_ContentType(t *testing.T) {
+	w, f := newTestWebhook(t, map[string]string{
+		"webhook_url":     "/alerts",
+		"webhook_body":    "{{.Content}}",
+		"webhook_headers": "content-type: text/plain",
+	})
+	f.contentType = "text/plain"
+
+	if err := w.Send(notify.NewEvent(notify.EventNodeBlocked)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,22 @@
+package wecom
+
+import (
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type WeCom struct {
+	WebhookURL string
+	Templates  *notify.Templates
+}
+
+type markdownReq struct {
+	MsgType  string `json:"msgtype"`
+	Markdown struct {
+		Content string `json:"content"`
+	} `json:"markdown"`
+}
+
+type robotResp struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
@@ -0,0 +1,74 @@
+package wecom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// severityColors are the font colors of the severities, wecom markdown only has info (green), comment (grey) and
+// warning (orange)
+var severityColors = map[notify.Severity]string{
+	notify.Info:    "info",
+	notify.Warning: "comment",
+	notify.Error:   "warning",
+}
+
+func New(c map[string]string, templates *notify.Templates) (*WeCom, error) {
+	w := &WeCom{
+		WebhookURL: c[strings.ToLower("WECOM_WEBHOOK_URL")],
+		Templates:  templates,
+	}
+	if w.WebhookURL == "" {
+		return nil, errors.New("wecom webhook url is empty")
+	}
+
+	return w, nil
+}
+
+// Send posts the event as a markdown message, the title is colored by severity
+func (w *WeCom) Send(e *notify.Event) error {
+	title, content, err := w.Templates.Render(e)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		fmt.Sprintf("### <font color=\"%s\">%s</font>", severityColors[e.Severity], title),
+		content,
+	}
+	for _, f := range e.Fields() {
+		lines = append(lines, fmt.Sprintf("> %s: <font color=\"comment\">%s</font>", f.Name, f.Value))
+	}
+
+	return w.post(strings.Join(lines, "\n"))
+}
+
+func (w *WeCom) post(markdown string) error {
+	req := &markdownReq{MsgType: "markdown"}
+	req.Markdown.Content = markdown
+
+	rtn := &robotResp{}
+	resp, err := resty.New().SetTimeout(time.Second * 10).SetRetryCount(3).R().
+		SetResult(rtn).
+		ForceContentType("application/json").
+		SetBody(req).
+		Post(w.WebhookURL)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("[WeCom] %d: %s", resp.StatusCode(), resp.String())
+	}
+	if rtn.ErrCode != 0 {
+		return fmt.Errorf("[WeCom] %d: %s", rtn.ErrCode, rtn.ErrMsg)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package wecom
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+type fakeWeCom struct {
+	reqs []markdownReq
+}
+
+func (f *fakeWeCom) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("key") != "key" {
+		_, _ = w.Write([]byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`))
+		return
+	}
+
+	req := markdownReq{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.reqs = append(f.reqs, req)
+	_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+}
+
+func TestWeCom_Send(t *testing.T) {
+	f := &fakeWeCom{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	w, err := New(map[string]string{"wecom_webhook_url": srv.URL + "/cgi-bin/webhook/send?key=key"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := notify.NewEvent(notify.EventRotationFailed)
+	e.Node, e.NewIP, e.Attempts = "node1.test.com(tcp4)", "2.2.2.2", 3
+	if err := w.Send(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].MsgType != "markdown" ||
+		!strings.HasPrefix(f.reqs[0].Markdown.Content, `### <font color="warning">node1.test.com(tcp4)</font>`) ||
+		!strings.Contains(f.reqs[0].Markdown.Content, `> New IP: <font color="comment">2.2.2.2</font>`) {
+		t.Errorf("unexpected requests: %+v", f.reqs)
+	}
+
+	w.WebhookURL = srv.URL + "/cgi-bin/webhook/send?key=wrong"
+	if err := w.Send(notify.NewEvent(notify.EventServiceStarted)); err == nil || err.Error() != "[WeCom] 93000: invalid webhook url" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -1,15 +1,19 @@
 package config
 
 type Config struct {
-	LogLevel   string
-	Internal   int
-	Timeout    int
-	Nameserver string
-	Concurrent int
-	Ipv6       bool
-	DDNS       *DDNS
-	Notify     *Notify
-	Nodes      []*Node
+	LogLevel     string
+	Internal     int
+	Timeout      int
+	Nameserver   string
+	Propagation  int
+	Concurrent   int
+	Ipv6         bool
+	Report       string
+	Connectivity *Connectivity
+	DDNS         *DDNS
+	Notify       *Notify
+	Pools        []*Pool
+	Nodes        []*Node
 }
 
 type Node struct {
@@ -19,17 +23,76 @@ type Node struct {
 	InstanceName    string
 	Network         []string
 	Domain          string
+	Domains         []*Domain
+	Pool            string
+	Standby         string
 	Port            int
 }
 
+// Connectivity are the targets to check the local network per address family, an HTTP url, a "host:port" address or
+// a "dns://server/domain" query. The policy is any, all, majority or the minimum number of reachable targets. The
+// node block checks are skipped for Grace seconds after an outage.
+type Connectivity struct {
+	Policy string
+	Ipv4   []string
+	Ipv6   []string
+	Grace  int
+}
+
+// Pool is a domain shared by several nodes, its records are the IPs of the healthy members
+type Pool struct {
+	Name   string
+	Domain string
+	DDNS   string
+}
+
+// Domain is a node domain updated by the named DDNS provider
+type Domain struct {
+	Name string
+	DDNS string
+}
+
 type DDNS struct {
-	Enable   bool
+	Enable    bool
+	Provider  string
+	Config    map[string]string
+	Providers []*DDNSProvider
+}
+
+// DDNSProvider is a DDNS provider instance that domains refer to by name
+type DDNSProvider struct {
+	Name     string
 	Provider string
 	Config   map[string]string
 }
 
 type Notify struct {
-	Enable   bool
-	Provider string
-	Config   map[string]string
+	Enable    bool
+	Provider  string
+	Config    map[string]string
+	Timeout   int
+	Templates map[string]string
+	Notifiers []*Notifier
+	Pipeline  `mapstructure:",squash"`
+}
+
+// Notifier is a notification channel, which only receives the listed events at or above the severity. The templates
+// override the message templates by event type.
+type Notifier struct {
+	Name      string
+	Provider  string
+	Config    map[string]string
+	Events    []string
+	Severity  string
+	Templates map[string]string
+	Pipeline  `mapstructure:",squash"`
+}
+
+// Pipeline drops the identical events within Dedupe seconds, sends at most RateLimit events per RatePeriod seconds,
+// and batches the events below the error severity into a digest every Digest seconds. Zero disables a stage.
+type Pipeline struct {
+	Dedupe     int
+	RateLimit  int
+	RatePeriod int
+	Digest     int
 }
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Septrum101/lightsailMon/app/node"
+	"github.com/Septrum101/lightsailMon/common/notify/telegram"
+)
+
+// startBots registers the commands and polls the bots until ctx is done
+func (s *Service) startBots(ctx context.Context) {
+	for _, b := range s.bots {
+		b.Handle("status", "", s.statusCommand)
+		b.Handle("check", "", s.checkCommand)
+		b.Handle("rotate", "<domain>", s.rotateCommand)
+		b.Handle("pause", "<domain>", s.pauseCommand(true))
+		b.Handle("resume", "<domain>", s.pauseCommand(false))
+
+		go b.Run(ctx)
+	}
+}
+
+// statusCommand replies the IP, latency and last rotation of every node
+func (s *Service) statusCommand([]string) (string, error) {
+	var lines []string
+	for _, n := range s.nodes {
+		st := n.Status()
+
+		state := fmt.Sprintf("%d ms", st.Latency)
+		switch {
+		case st.Paused:
+			state = "paused"
+		case st.Blocked:
+			state = "blocked"
+		}
+
+		rotation := "never"
+		if !st.LastRotation.IsZero() {
+			rotation = st.LastRotation.Format(time.DateTime)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s\nIP: %s, %s, last rotation: %s", st.Name, st.IP, state, rotation))
+	}
+
+	return strings.Join(lines, "\n\n"), nil
+}
+
+// checkCommand starts a check of all nodes in the background
+func (s *Service) checkCommand([]string) (string, error) {
+	if !s.runMu.TryLock() {
+		return "A check is already running", nil
+	}
+
+	go func() {
+		defer s.runMu.Unlock()
+		s.run()
+	}()
+
+	return "Check started", nil
+}
+
+// rotateCommand renews the IPs of the matched nodes in the background
+func (s *Service) rotateCommand(args []string) (string, error) {
+	nodes, err := s.matchNodes(args)
+	if err != nil {
+		return "", err
+	}
+	if !s.runMu.TryLock() {
+		return "A check is running, try again later", nil
+	}
+
+	go func() {
+		defer s.runMu.Unlock()
+		s.changeNodeIps(nodes)
+		s.syncPools()
+	}()
+
+	return fmt.Sprintf("Rotating %d node(s) of %s", len(nodes), args[0]), nil
+}
+
+// pauseCommand pauses or resumes the checks of the matched nodes
+func (s *Service) pauseCommand(pause bool) func([]string) (string, error) {
+	return func(args []string) (string, error) {
+		nodes, err := s.matchNodes(args)
+		if err != nil {
+			return "", err
+		}
+
+		for _, n := range nodes {
+			if pause {
+				n.Pause()
+			} else {
+				n.Resume()
+			}
+		}
+
+		if pause {
+			return fmt.Sprintf("Paused %d node(s) of %s", len(nodes), args[0]), nil
+		}
+		return fmt.Sprintf("Resumed %d node(s) of %s", len(nodes), args[0]), nil
+	}
+}
+
+// matchNodes returns the nodes of the domain or instance name in args
+func (s *Service) matchNodes(args []string) ([]*node.Node, error) {
+	if len(args) != 1 {
+		return nil, telegram.ErrUsage
+	}
+
+	var nodes []*node.Node
+	for _, n := range s.nodes {
+		if n.Match(args[0]) {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("node %s is not found", args[0])
+	}
+
+	return nodes, nil
+}
@@ -1,74 +1,429 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Septrum101/lightsailMon/app/node"
+	"github.com/Septrum101/lightsailMon/common/connectivity"
 	"github.com/Septrum101/lightsailMon/common/ddns"
+	"github.com/Septrum101/lightsailMon/common/ddns/alidns"
 	"github.com/Septrum101/lightsailMon/common/ddns/cloudflare"
-	"github.com/Septrum101/lightsailMon/common/ddns/google"
+	"github.com/Septrum101/lightsailMon/common/ddns/dnspod"
+	"github.com/Septrum101/lightsailMon/common/ddns/dyndns2"
+	"github.com/Septrum101/lightsailMon/common/ddns/webhook"
 	"github.com/Septrum101/lightsailMon/common/notify"
+	"github.com/Septrum101/lightsailMon/common/notify/bark"
+	"github.com/Septrum101/lightsailMon/common/notify/dingtalk"
+	"github.com/Septrum101/lightsailMon/common/notify/discord"
+	"github.com/Septrum101/lightsailMon/common/notify/feishu"
+	"github.com/Septrum101/lightsailMon/common/notify/gotify"
+	"github.com/Septrum101/lightsailMon/common/notify/ntfy"
 	"github.com/Septrum101/lightsailMon/common/notify/pushplus"
+	"github.com/Septrum101/lightsailMon/common/notify/slack"
+	"github.com/Septrum101/lightsailMon/common/notify/smtp"
 	"github.com/Septrum101/lightsailMon/common/notify/telegram"
+	notifywebhook "github.com/Septrum101/lightsailMon/common/notify/webhook"
+	"github.com/Septrum101/lightsailMon/common/notify/wecom"
+	"github.com/Septrum101/lightsailMon/config"
 )
 
-func (s *Service) buildNodes(isNotify bool, isDDNS bool) []*node.Node {
-	// init notifier
-	var notifier notify.Notify
-	if isNotify {
-		switch s.conf.Notify.Provider {
-		case "pushplus":
-			notifier = &pushplus.PushPlus{Token: s.conf.Notify.Config["pushplus_token"]}
-		case "telegram":
-			notifier = &telegram.Telegram{
-				ApiHost: s.conf.Notify.Config["telegram_apihost"],
-				ChatID:  s.conf.Notify.Config["telegram_chatid"],
-				Token:   s.conf.Notify.Config["telegram_token"],
-			}
-		}
-	}
-
-	// init ddnsCli
-	var ddnsCli ddns.Client
-	if isDDNS {
-		var err error
-		switch s.conf.DDNS.Provider {
-		case "cloudflare":
-			if ddnsCli, err = cloudflare.New(s.conf.DDNS.Config); err != nil {
-				log.Panicln(err)
-			}
-		case "google":
-			if ddnsCli, err = google.New(s.conf.DDNS.Config); err != nil {
-				log.Panicln(err)
-			}
-		}
-	}
+const (
+	// defaultDDNSName names the legacy single DDNS provider
+	defaultDDNSName = "default"
+	// defaultNotifierName names the legacy single notifier
+	defaultNotifierName = "default"
+	// defaultIpv4Target and defaultIpv6Target check the local network when no target is configured
+	defaultIpv4Target = "http://www.baidu.com/favicon.ico"
+	defaultIpv6Target = "http://www.baidu.com"
+	// defaultGrace is the time to skip the node block checks after a local network outage
+	defaultGrace = time.Minute * 5
+)
 
+func (s *Service) buildNodes(notifier *notify.Multi, ddnsClients map[string]ddns.Client) []*node.Node {
 	var nodes []*node.Node
 	for i := range s.conf.Nodes {
 		newNodes := node.New(s.conf.Nodes[i])
 		for ii := range newNodes {
 			newNode := newNodes[ii]
-			// set ddns client
-			if isDDNS {
-				newNode.DdnsClient = ddnsCli
+			// set ddns client of each domain, the default provider is used if none is named
+			if ddnsClients != nil {
+				for _, d := range newNode.Domains {
+					d.DdnsClient = s.ddnsClient(ddnsClients, d.Name, d.DDNS)
+				}
 			}
 
 			// set notifier
-			if isNotify {
-				newNode.Notifier = notifier
-			}
+			newNode.Notifier = notifier
 
 			// set connection timeout
 			if s.conf.Timeout > 0 {
 				newNode.Timeout = time.Second * time.Duration(s.conf.Timeout)
 			}
 
+			// set dns propagation check, a negative timeout disables it
+			newNode.Nameserver = s.conf.Nameserver
+			if s.conf.Propagation != 0 {
+				newNode.PropagationTimeout = time.Second * time.Duration(s.conf.Propagation)
+			}
+
 			nodes = append(nodes, newNode)
 		}
 	}
+	s.linkStandby(nodes)
+	s.markExclusive(nodes)
 
 	return nodes
 }
+
+// markExclusive marks the node domains that no node or pool serves on the other network, only their records of the
+// other network are removed
+func (s *Service) markExclusive(nodes []*node.Node) {
+	served := make(map[string]bool)
+	for _, n := range nodes {
+		for _, d := range n.Domains {
+			served[n.Network+"|"+d.Name] = true
+		}
+		if n.Pool == "" {
+			continue
+		}
+		for _, p := range s.conf.Pools {
+			if p.Name == n.Pool {
+				served[n.Network+"|"+p.Domain] = true
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		other := "tcp6"
+		if n.Network == "tcp6" {
+			other = "tcp4"
+		}
+		for _, d := range n.Domains {
+			d.Exclusive = !served[other+"|"+d.Name]
+		}
+	}
+}
+
+// linkStandby resolves the standby of each node, which is either a fixed IP or the instance name of another node on
+// the same network
+func (s *Service) linkStandby(nodes []*node.Node) {
+	for _, c := range s.conf.Nodes {
+		if c.Standby == "" {
+			continue
+		}
+
+		for _, n := range nodes {
+			if n.Name() != c.InstanceName {
+				continue
+			}
+
+			if addr, err := netip.ParseAddr(c.Standby); err == nil {
+				if addr.Is4() != (n.Network == "tcp4") {
+					log.Panicf("%s: standby %s does not match network %s", c.InstanceName, c.Standby, n.Network)
+				}
+				n.StandbyIP = addr.String()
+				continue
+			}
+
+			idx := slices.IndexFunc(nodes, func(sn *node.Node) bool {
+				return sn.Name() == c.Standby && sn.Network == n.Network
+			})
+			if idx < 0 || c.Standby == c.InstanceName {
+				log.Panicf("%s: standby node %s(%s) is not found", c.InstanceName, c.Standby, n.Network)
+			}
+			n.StandbyNode = nodes[idx]
+		}
+	}
+}
+
+// buildPools groups the nodes of each pool by network, the pool domain is updated by the named DDNS provider
+func (s *Service) buildPools(nodes []*node.Node, ddnsClients map[string]ddns.Client) []*node.Pool {
+	var pools []*node.Pool
+	for _, p := range s.conf.Pools {
+		if ddnsClients == nil {
+			log.Panicf("pool %s: ddns is not enabled", p.Name)
+		}
+		if p.Domain == "" {
+			log.Panicf("pool %s: domain is empty", p.Name)
+		}
+
+		cli := s.ddnsClient(ddnsClients, p.Domain, p.DDNS)
+		for _, network := range []string{"tcp4", "tcp6"} {
+			var members []*node.Node
+			for _, n := range nodes {
+				if n.Pool == p.Name && n.Network == network {
+					members = append(members, n)
+				}
+			}
+			if len(members) == 0 {
+				continue
+			}
+
+			pool := node.NewPool(p.Name, p.Domain, network, members)
+			pool.DdnsClient = cli
+			pools = append(pools, pool)
+		}
+	}
+
+	// a node must not refer to an unknown pool
+	for _, n := range nodes {
+		if n.Pool != "" && !slices.ContainsFunc(s.conf.Pools, func(p *config.Pool) bool { return p.Name == n.Pool }) {
+			log.Panicf("pool %s is not found", n.Pool)
+		}
+	}
+
+	return pools
+}
+
+// ddnsClient returns the named DDNS client of domain, the default provider is used if none is named
+func (s *Service) ddnsClient(ddnsClients map[string]ddns.Client, domain string, name string) ddns.Client {
+	if name == "" {
+		name = s.defaultDDNS()
+	}
+	cli, ok := ddnsClients[name]
+	if !ok {
+		log.Panicf("%s: ddns provider %s is not found", domain, name)
+	}
+	return cli
+}
+
+// buildDDNSClients creates a client for each configured DDNS provider, keyed by provider name
+func (s *Service) buildDDNSClients() map[string]ddns.Client {
+	providers := s.conf.DDNS.Providers
+	if s.conf.DDNS.Provider != "" {
+		providers = append([]*config.DDNSProvider{{
+			Name:     defaultDDNSName,
+			Provider: s.conf.DDNS.Provider,
+			Config:   s.conf.DDNS.Config,
+		}}, providers...)
+	}
+
+	clients := make(map[string]ddns.Client)
+	for _, p := range providers {
+		if _, ok := clients[p.Name]; ok {
+			log.Panicf("duplicate ddns provider name: %s", p.Name)
+		}
+
+		var (
+			cli ddns.Client
+			err error
+		)
+		switch p.Provider {
+		case "cloudflare":
+			cli, err = cloudflare.New(p.Config)
+		case "dnspod":
+			cli, err = dnspod.New(p.Config)
+		case "alidns":
+			cli, err = alidns.New(p.Config)
+		case "dyndns2":
+			cli, err = dyndns2.New(p.Config)
+		case "webhook":
+			cli, err = webhook.New(p.Config)
+		default:
+			err = fmt.Errorf("not support ddns provider: %s", p.Provider)
+		}
+		if err != nil {
+			log.Panicln(p.Name, err)
+		}
+		clients[p.Name] = cli
+	}
+
+	return clients
+}
+
+// buildCheckers creates the local connectivity checkers, the targets default to the ones before they were configurable
+func (s *Service) buildCheckers() (*connectivity.Checker, *connectivity.Checker) {
+	c := s.conf.Connectivity
+	if c == nil {
+		c = &config.Connectivity{}
+	}
+	ipv4, ipv6 := c.Ipv4, c.Ipv6
+	if len(ipv4) == 0 {
+		ipv4 = []string{defaultIpv4Target}
+	}
+	if len(ipv6) == 0 {
+		ipv6 = []string{defaultIpv6Target}
+	}
+
+	timeout := time.Duration(s.timeout) * time.Second
+	ipv4Checker, err := connectivity.New("tcp4", ipv4, c.Policy, timeout)
+	if err != nil {
+		log.Panic(err)
+	}
+	ipv6Checker, err := connectivity.New("tcp6", ipv6, c.Policy, timeout)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return ipv4Checker, ipv6Checker
+}
+
+// buildNotifier creates a channel for each configured notifier, the messages are sent to all of them
+func (s *Service) buildNotifier() *notify.Multi {
+	notifiers := s.conf.Notify.Notifiers
+	if s.conf.Notify.Provider != "" {
+		notifiers = append([]*config.Notifier{{
+			Name:     defaultNotifierName,
+			Provider: s.conf.Notify.Provider,
+			Config:   s.conf.Notify.Config,
+			Pipeline: s.conf.Notify.Pipeline,
+		}}, notifiers...)
+	}
+
+	m := &notify.Multi{Timeout: time.Second * time.Duration(s.conf.Notify.Timeout)}
+	for _, n := range notifiers {
+		if slices.ContainsFunc(m.Channels, func(c *notify.Channel) bool { return c.Name == n.Name }) {
+			log.Panicf("duplicate notifier name: %s", n.Name)
+		}
+
+		severity, err := notify.ParseSeverity(n.Severity)
+		if err != nil {
+			log.Panicln(n.Name, err)
+		}
+
+		var events []notify.EventType
+		for _, e := range n.Events {
+			if !slices.Contains(notify.EventTypes, notify.EventType(e)) {
+				log.Panicf("%s: unknown event: %s", n.Name, e)
+			}
+			events = append(events, notify.EventType(e))
+		}
+
+		// the notifier templates override the shared ones
+		overrides := maps.Clone(s.conf.Notify.Templates)
+		if overrides == nil {
+			overrides = make(map[string]string)
+		}
+		maps.Copy(overrides, n.Templates)
+		templates, err := notify.NewTemplates(overrides)
+		if err != nil {
+			log.Panicln(n.Name, err)
+		}
+
+		var notifier notify.Notify
+		switch n.Provider {
+		case "pushplus":
+			notifier, err = pushplus.New(n.Config, templates)
+		case "telegram":
+			var t *telegram.Telegram
+			if t, err = newTelegram(n.Config, templates); err == nil {
+				notifier = t
+				s.addBot(t, n.Config)
+			}
+		case "discord":
+			notifier, err = discord.New(n.Config, templates)
+		case "slack":
+			notifier, err = slack.New(n.Config, templates)
+		case "smtp":
+			notifier, err = smtp.New(n.Config, templates)
+		case "ntfy":
+			notifier, err = ntfy.New(n.Config, templates)
+		case "gotify":
+			notifier, err = gotify.New(n.Config, templates)
+		case "bark":
+			notifier, err = bark.New(n.Config, templates)
+		case "wecom":
+			notifier, err = wecom.New(n.Config, templates)
+		case "dingtalk":
+			notifier, err = dingtalk.New(n.Config, templates)
+		case "feishu":
+			notifier, err = feishu.New(n.Config, templates)
+		case "webhook":
+			notifier, err = notifywebhook.New(n.Config, templates)
+		default:
+			err = fmt.Errorf("not support notifier provider: %s", n.Provider)
+		}
+		if err != nil {
+			log.Panicln(n.Name, err)
+		}
+
+		m.Channels = append(m.Channels, &notify.Channel{
+			Name:     n.Name,
+			Notifier: pipeline(notifier, n.Pipeline),
+			Events:   events,
+			Severity: severity,
+		})
+	}
+
+	return m
+}
+
+// pipeline wraps the notifier with the enabled stages, the identical events are dropped before they are batched
+func pipeline(n notify.Notify, p config.Pipeline) notify.Notify {
+	if p.RateLimit > 0 {
+		period := time.Second * time.Duration(p.RatePeriod)
+		if period <= 0 {
+			period = time.Minute
+		}
+		n = notify.NewThrottle(n, p.RateLimit, period)
+	}
+	if p.Digest > 0 {
+		n = notify.NewDigest(n, time.Second*time.Duration(p.Digest))
+	}
+	if p.Dedupe > 0 {
+		n = notify.NewDedupe(n, time.Second*time.Duration(p.Dedupe))
+	}
+	return n
+}
+
+func newTelegram(c map[string]string, templates *notify.Templates) (*telegram.Telegram, error) {
+	t := &telegram.Telegram{
+		ApiHost:   c[strings.ToLower("TELEGRAM_APIHOST")],
+		ChatID:    c[strings.ToLower("TELEGRAM_CHATID")],
+		Token:     c[strings.ToLower("TELEGRAM_TOKEN")],
+		ParseMode: c[strings.ToLower("TELEGRAM_PARSE_MODE")],
+		Templates: templates,
+	}
+	if t.ChatID == "" || t.Token == "" {
+		return nil, errors.New("telegram chat id or token is empty")
+	}
+	if t.ParseMode != "" && t.ParseMode != "MarkdownV2" && t.ParseMode != "HTML" {
+		return nil, fmt.Errorf("not support telegram parse mode: %s", t.ParseMode)
+	}
+
+	if id := c[strings.ToLower("TELEGRAM_THREAD_ID")]; id != "" {
+		threadID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("telegram thread id: %w", err)
+		}
+		t.ThreadID = threadID
+	}
+
+	silent, err := notify.ParseSeverity(c[strings.ToLower("TELEGRAM_SILENT_BELOW")])
+	if err != nil {
+		return nil, err
+	}
+	t.SilentBelow = silent
+
+	return t, nil
+}
+
+// addBot enables the bot commands of the telegram notifier, the notification chat is allowed by default
+func (s *Service) addBot(t *telegram.Telegram, c map[string]string) {
+	if enable, _ := strconv.ParseBool(c[strings.ToLower("TELEGRAM_BOT")]); !enable {
+		return
+	}
+
+	chats := []string{t.ChatID}
+	if allowed := c[strings.ToLower("TELEGRAM_BOT_CHATS")]; allowed != "" {
+		chats = strings.Split(strings.ReplaceAll(allowed, " ", ""), ",")
+	}
+	s.bots = append(s.bots, telegram.NewBot(t, chats))
+}
+
+// defaultDDNS returns the name of the provider used by domains that do not name one
+func (s *Service) defaultDDNS() string {
+	if s.conf.DDNS.Provider != "" || len(s.conf.DDNS.Providers) == 0 {
+		return defaultDDNSName
+	}
+	return s.conf.DDNS.Providers[0].Name
+}
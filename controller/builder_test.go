@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/Septrum101/lightsailMon/app/node"
+	"github.com/Septrum101/lightsailMon/config"
+)
+
+func TestService_markExclusive(t *testing.T) {
+	s := &Service{conf: &config.Config{Pools: []*config.Pool{{Name: "hk", Domain: "pool.example.com"}}}}
+
+	// shared.example.com is served on ipv6 by another node, pool.example.com by the pool members
+	v4 := &node.Node{Network: "tcp4", Domains: []*node.Domain{
+		{Name: "only.example.com"}, {Name: "shared.example.com"}, {Name: "pool.example.com"},
+	}}
+	v6 := &node.Node{Network: "tcp6", Pool: "hk", Domains: []*node.Domain{{Name: "shared.example.com"}}}
+	s.markExclusive([]*node.Node{v4, v6})
+
+	for _, c := range []struct {
+		d    *node.Domain
+		want bool
+	}{
+		{v4.Domains[0], true},
+		{v4.Domains[1], false},
+		{v4.Domains[2], false},
+		{v6.Domains[0], false},
+	} {
+		if c.d.Exclusive != c.want {
+			t.Errorf("%s: expected exclusive %t, got %t", c.d.Name, c.want, c.d.Exclusive)
+		}
+	}
+}
@@ -3,8 +3,6 @@ package controller
 import (
 	"context"
 	"fmt"
-	"net"
-	"net/http"
 	"strings"
 	"time"
 
@@ -15,17 +13,22 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Septrum101/lightsailMon/app/node"
+	"github.com/Septrum101/lightsailMon/common/connectivity"
+	"github.com/Septrum101/lightsailMon/common/ddns"
+	"github.com/Septrum101/lightsailMon/common/notify"
 	"github.com/Septrum101/lightsailMon/config"
 )
 
 func New(c *config.Config) *Service {
 	s := &Service{
-		conf:     c,
-		cron:     cron.New(),
-		internal: c.Internal,
-		timeout:  c.Timeout,
-		worker:   make(chan bool, c.Concurrent),
-		cli:      resty.New().SetLogger(log.StandardLogger()).SetRetryCount(3),
+		conf:        c,
+		cron:        cron.New(),
+		internal:    c.Internal,
+		timeout:     c.Timeout,
+		worker:      make(chan bool, c.Concurrent),
+		cli:         resty.New().SetLogger(log.StandardLogger()).SetRetryCount(3),
+		networkDown: make(map[string]time.Time),
+		recovered:   make(map[string]time.Time),
 	}
 
 	// init log level
@@ -43,20 +46,53 @@ func New(c *config.Config) *Service {
 
 	ddnsStatus := "off"
 	if isDDNS {
-		ddnsStatus = strings.Title(c.DDNS.Provider)
+		var providers []string
+		if c.DDNS.Provider != "" {
+			providers = append(providers, strings.Title(c.DDNS.Provider))
+		}
+		for _, p := range c.DDNS.Providers {
+			providers = append(providers, fmt.Sprintf("%s(%s)", p.Name, strings.Title(p.Provider)))
+		}
+		ddnsStatus = strings.Join(providers, ", ")
 	}
 	notifierStatus := "off"
 	if isNotify {
-		notifierStatus = strings.Title(c.Notify.Provider)
+		var notifiers []string
+		if c.Notify.Provider != "" {
+			notifiers = append(notifiers, strings.Title(c.Notify.Provider))
+		}
+		for _, n := range c.Notify.Notifiers {
+			notifiers = append(notifiers, fmt.Sprintf("%s(%s)", n.Name, strings.Title(n.Provider)))
+		}
+		notifierStatus = strings.Join(notifiers, ", ")
 	}
 	fmt.Printf("Log level: %s, Concurrent: %d, DDNS: %s, Notifier: %s, IPv6: %t\n", c.LogLevel, c.Concurrent,
 		ddnsStatus, notifierStatus, c.Ipv6)
 
-	nodes := s.buildNodes(isNotify, isDDNS)
+	s.ipv4Checker, s.ipv6Checker = s.buildCheckers()
+	s.grace = defaultGrace
+	if c.Connectivity != nil && c.Connectivity.Grace != 0 {
+		// a negative grace disables it
+		s.grace = time.Duration(c.Connectivity.Grace) * time.Second
+	}
+
+	// init ddns clients
+	var ddnsClients map[string]ddns.Client
+	if isDDNS {
+		ddnsClients = s.buildDDNSClients()
+	}
+
+	// init notifier
+	if isNotify {
+		s.notifier = s.buildNotifier()
+	}
+
+	nodes := s.buildNodes(s.notifier, ddnsClients)
 	if len(nodes) == 0 {
 		log.Panic("no valid node")
 	}
 	s.nodes = nodes
+	s.pools = s.buildPools(nodes, ddnsClients)
 
 	return s
 }
@@ -74,8 +110,21 @@ func (s *Service) Start() {
 		log.Panic(err)
 	}
 
+	// scheduled summary report
+	if s.conf.Report != "" {
+		if _, err := s.cron.AddFunc(s.conf.Report, s.report); err != nil {
+			log.Panicf("invalid report schedule %q: %v", s.conf.Report, err)
+		}
+	}
+
 	s.cron.Start()
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.startBots(ctx)
+
 	log.Warnln(config.AppName, "Started")
+	s.notify(notify.NewEvent(notify.EventServiceStarted))
 }
 
 func (s *Service) Close() {
@@ -85,73 +134,113 @@ func (s *Service) Close() {
 		s.cron.Remove(entry[i].ID)
 	}
 	s.cron.Stop()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	// wait for the check or rotation started by the bots, the lock is kept so no run starts after the worker is closed
+	s.runMu.Lock()
 	close(s.worker)
 	s.running = false
+	s.notify(notify.NewEvent(notify.EventServiceStopped))
+	if s.notifier != nil {
+		// flush the digests
+		if err := s.notifier.Close(); err != nil {
+			log.Errorln("Close notifier:", err)
+		}
+	}
 }
 
-func (s *Service) Run() {
-	// check local network connectivity
-	if !s.checkIpv4() {
+// notify sends a service event
+func (s *Service) notify(e *notify.Event) {
+	if s.notifier == nil {
 		return
 	}
 
-	if s.conf.Ipv6 && s.checkIpv6() {
-		s.isIpv6 = true
-	} else {
-		s.isIpv6 = false
+	e.Node = config.AppName
+	if err := s.notifier.Send(e); err != nil {
+		log.Errorf("Push %s event: %v", e.Type, err)
 	}
+}
 
-	s.changeNodeIps(s.getBlockNodes())
+func (s *Service) Run() {
+	if !s.runMu.TryLock() {
+		log.Warn("The previous check is still running, skip")
+		return
+	}
+	defer s.runMu.Unlock()
+
+	s.run()
 }
 
-func (s *Service) checkIpv4() bool {
-	dialer := &net.Dialer{}
-	client := resty.New().SetTimeout(time.Duration(s.timeout) * time.Second)
-	client.SetTransport(&http.Transport{
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialer.DialContext(ctx, "tcp4", addr)
-		},
-	})
-
-	start := time.Now()
-	resp, err := client.R().Get("http://www.baidu.com/favicon.ico")
-	if err != nil {
-		log.Error(err)
-		return false
+func (s *Service) run() {
+	// check local network connectivity, the state transitions are notified once
+	if !s.checkNetwork(s.ipv4Checker) {
+		return
 	}
-	delay := time.Since(start)
-	if resp.StatusCode() > 299 {
-		log.Error(resp.String())
-		return false
+
+	s.isIpv6 = false
+	if s.conf.Ipv6 {
+		s.isIpv6 = s.checkNetwork(s.ipv6Checker)
+	}
+
+	blockNodes := s.getBlockNodes()
+
+	// pull the blocked members out of their pools before rotating them, and add them back with the new IPs
+	s.syncPools()
+	if len(blockNodes) > 0 {
+		s.changeNodeIps(blockNodes)
+		s.syncPools()
 	}
-	log.WithField("domian", "ipv4.connectivity").Infof("Tcping: %d ms", delay.Milliseconds())
-	return true
 }
 
-func (s *Service) checkIpv6() bool {
-	dialer := &net.Dialer{}
-	client := resty.New().SetTimeout(time.Duration(s.timeout) * time.Second)
-	client.SetTransport(&http.Transport{
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialer.DialContext(ctx, "tcp6", addr)
-		},
-	})
-
-	start := time.Now()
-	resp, err := client.R().Get("http://www.baidu.com")
+func (s *Service) syncPools() {
+	for _, p := range s.pools {
+		if err := p.Sync(); err != nil {
+			p.Logger.Errorf("Failed to sync pool records: %v", err)
+		}
+	}
+}
+
+// checkNetwork checks the local network, and notifies once when it goes down and when it comes back with the outage
+// duration
+func (s *Service) checkNetwork(c *connectivity.Checker) bool {
+	logger := log.WithField("domain", c.Network+".connectivity")
+	err := c.Check(context.Background())
+	since, wasDown := s.networkDown[c.Network]
+
 	if err != nil {
-		log.Error(err)
+		logger.Error(err)
+		if !wasDown {
+			s.networkDown[c.Network] = time.Now()
+			e := notify.NewEvent(notify.EventNetworkDown).WithError(err)
+			e.Network = c.Network
+			s.notify(e)
+		}
 		return false
 	}
-	delay := time.Since(start)
-	if resp.StatusCode() > 299 {
-		log.Error(resp.String())
-		return false
+
+	logger.Info("Local network is up")
+	if wasDown {
+		outage := time.Since(since).Round(time.Second)
+		logger.Warnf("Local network recovered after %s", outage)
+		delete(s.networkDown, c.Network)
+		s.recovered[c.Network] = time.Now()
+
+		e := notify.NewEvent(notify.EventNetworkUp)
+		e.Network = c.Network
+		e.Duration = outage
+		s.notify(e)
 	}
-	log.WithField("domian", "ipv6.connectivity").Infof("Tcping: %d ms", delay.Milliseconds())
 	return true
 }
 
+// inGrace reports whether the local network recovered within the grace period, the nodes look blocked until the
+// network settles
+func (s *Service) inGrace(network string) bool {
+	t, ok := s.recovered[network]
+	return ok && time.Since(t) < s.grace
+}
+
 func (s *Service) changeNodeIps(blockNodes []*node.Node) {
 	if len(blockNodes) > 0 {
 		// get blocked node lightsail service
@@ -202,6 +291,9 @@ func (s *Service) allocateStaticIps(svcMap map[*lightsail.Client]bool) {
 func (s *Service) getBlockNodes() []*node.Node {
 	nodesChan := make(chan *node.Node)
 
+	// avoid a rotation storm right after a local network outage
+	grace := map[string]bool{"tcp4": s.inGrace("tcp4"), "tcp6": s.inGrace("tcp6")}
+
 	// get block nodes
 	for i := range s.nodes {
 		s.worker <- true
@@ -213,19 +305,36 @@ func (s *Service) getBlockNodes() []*node.Node {
 				s.wg.Done()
 			}()
 
+			if n.Paused() {
+				n.Logger.Info("Node is paused, skip check")
+				return
+			}
+
 			// check host ipv6 is availiable
 			if n.Network == "tcp6" && !s.isIpv6 {
 				n.Logger.Error("Host's ipv6 network is not supported")
 				return
 			}
 
-			go func() {
-				if err := n.UpdateDomainIp(); err != nil {
-					n.Logger.Errorf("Failed to update domain IP: %v", err)
+			// the domains are still synced in the grace period, only the block decision is skipped
+			blocked := false
+			if grace[n.Network] {
+				n.Logger.Info("Local network recovered recently, skip the block check")
+			} else {
+				blocked = n.IsBlock()
+			}
+			if blocked {
+				if err := n.Failover(); err != nil {
+					n.Logger.Errorf("Failed to failover to standby: %v", err)
 				}
-			}()
+			}
+
+			// sync the domains after the check, so they follow the state it has just decided
+			if err := n.UpdateDomainIp(); err != nil {
+				n.Logger.Errorf("Failed to update domain IP: %v", err)
+			}
 
-			if n.IsBlock() {
+			if blocked {
 				// add to blockNodes channel
 				nodesChan <- n
 			}
@@ -1,24 +1,39 @@
 package controller
 
 import (
+	"context"
 	"github.com/go-resty/resty/v2"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 
 	"github.com/Septrum101/lightsailMon/app/node"
+	"github.com/Septrum101/lightsailMon/common/connectivity"
+	"github.com/Septrum101/lightsailMon/common/notify"
+	"github.com/Septrum101/lightsailMon/common/notify/telegram"
 	"github.com/Septrum101/lightsailMon/config"
 )
 
 type Service struct {
-	conf     *config.Config
-	nodes    []*node.Node
-	cron     *cron.Cron
-	wg       sync.WaitGroup
-	cli      *resty.Client
-	running  bool
-	internal int
-	timeout  int
-	worker   chan bool
-	isIpv6   bool
+	conf        *config.Config
+	nodes       []*node.Node
+	pools       []*node.Pool
+	notifier    *notify.Multi
+	cron        *cron.Cron
+	wg          sync.WaitGroup
+	runMu       sync.Mutex
+	bots        []*telegram.Bot
+	cancel      context.CancelFunc
+	cli         *resty.Client
+	running     bool
+	internal    int
+	timeout     int
+	worker      chan bool
+	isIpv6      bool
+	ipv4Checker *connectivity.Checker
+	ipv6Checker *connectivity.Checker
+	networkDown map[string]time.Time // the start of the local network outages
+	recovered   map[string]time.Time // the end of the last local network outages
+	grace       time.Duration
 }
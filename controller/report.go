@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Septrum101/lightsailMon/common/notify"
+)
+
+// report sends the stats of every node since the last report
+func (s *Service) report() {
+	e := notify.NewEvent(notify.EventReport)
+	var since time.Time
+	for _, n := range s.nodes {
+		st := n.Stats()
+		if since.IsZero() || st.Since.Before(since) {
+			since = st.Since
+		}
+
+		e.Details = append(e.Details, fmt.Sprintf(
+			"%s\nIP: %s, lifetime: %s\nUptime: %.2f%% of %d checks, blocks: %d, rotations: %d\nLatency: avg %d ms, p95 %d ms",
+			st.Name, st.IP, st.IPLifetime.Round(time.Minute), st.Uptime, st.Checks, st.Blocks, st.Rotations,
+			st.AvgLatency, st.P95Latency))
+	}
+	e.Duration = time.Since(since).Round(time.Minute)
+
+	log.Info("Send summary report")
+	s.notify(e)
+}